@@ -1,49 +1,754 @@
 package main
 
-//type astPrinter struct{}
-//
-//func (x astPrinter) Print(expr *Expr) any {
-//	result, _ := expr.Accept(&x)
-//
-//	fmt.Println(result.(string))
-//
-//	return result
-//}
-//
-//func (x astPrinter) VisitBinaryExpr(expr *Binary) (any, error) {
-//	return x.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right), nil
-//}
-//
-//func (x astPrinter) VisitGroupingExpr(expr *Grouping) (any, error) {
-//	return x.parenthesize("group", expr.Expression), nil
-//}
-//
-//func (x astPrinter) VisitLiteralExpr(expr *Literal) (any, error) {
-//	if expr.Value == nil {
-//		return "nil", nil
-//	}
-//
-//	return fmt.Sprintf("%v", expr.Value), nil
-//}
-//
-//func (x astPrinter) VisitUnaryExpr(expr *Unary) (any, error) {
-//	return x.parenthesize(expr.Operator.Lexeme, expr.Right), nil
-//}
-//
-//func (x astPrinter) parenthesize(name string, exprs ...Expr) string {
-//	builder := strings.Builder{}
-//
-//	builder.WriteString("(")
-//	builder.WriteString(name)
-//
-//	for _, expr := range exprs {
-//		value, _ := expr.Accept(x)
-//
-//		builder.WriteString(" ")
-//		builder.WriteString(value.(string))
-//	}
-//
-//	builder.WriteString(")")
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Output formats understood by astPrinter.Print.
+const (
+	astFormatSExpr = "sexpr"
+	astFormatTree  = "tree"
+	astFormatJSON  = "json"
+	astFormatDot   = "dot"
+)
+
+var astFormats = []string{astFormatSExpr, astFormatTree, astFormatJSON, astFormatDot}
+
+// astPrinter turns statements into a format-neutral astNode tree and renders
+// it as S-expressions, an indented tree, JSON or Graphviz DOT.
+type astPrinter struct {
+	node *astNode // result of the last statement visit
+}
+
+func (x *astPrinter) Print(statements []Stmt, format string) (string, error) {
+	program := make([]*astNode, 0, len(statements))
+	for _, stmt := range statements {
+		program = append(program, x.stmt(stmt))
+	}
+
+	switch format {
+	case astFormatSExpr:
+		return x.renderSExpr(program), nil
+	case astFormatTree:
+		return x.renderTree(program), nil
+	case astFormatJSON:
+		return x.renderJSON(program)
+	case astFormatDot:
+		return x.renderDot(program), nil
+	}
+
+	return "", fmt.Errorf("unknown AST format '%s' (expected one of: %s)", format, strings.Join(astFormats, ", "))
+}
+
+// PrintExpr renders a single expression as an S-expression.
+func (x *astPrinter) PrintExpr(expr Expr) string {
+	return x.sexpr(x.expr(expr))
+}
+
+// region AST nodes
+type astNode struct {
+	kind     string // node type, e.g. "Binary" or "VarStmt"
+	head     string // S-expression head, e.g. "+" or "var a ="
+	atom     bool   // rendered as its bare head in S-expressions
+	line     int
+	attrs    []astAttr
+	children []astChild
+}
+
+type astAttr struct {
+	key   string
+	value any // string, float64, bool, nil or []string
+}
+
+type astChild struct {
+	key   string
+	list  bool
+	nodes []*astNode
+}
+
+func (n *astNode) attr(key string, value any) *astNode {
+	n.attrs = append(n.attrs, astAttr{key, value})
+
+	return n
+}
+
+func (n *astNode) child(key string, node *astNode) *astNode {
+	var nodes []*astNode
+	if node != nil {
+		nodes = []*astNode{node}
+	}
+
+	n.children = append(n.children, astChild{key: key, nodes: nodes})
+
+	return n
+}
+
+func (n *astNode) childList(key string, nodes []*astNode) *astNode {
+	n.children = append(n.children, astChild{key: key, list: true, nodes: nodes})
+
+	return n
+}
+
+func (n *astNode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	write := func(key string, value any) error {
+		buf.WriteString(",")
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteString(":")
+
+		// Source like a > b is written as is rather than as a \u003e b.
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+
+		buf.Truncate(buf.Len() - 1) // Encode ends with a newline
+
+		return nil
+	}
+
+	buf.WriteString(`{"type":`)
+	buf.WriteString(strconv.Quote(n.kind))
+
+	if n.line > 0 {
+		if err := write("line", n.line); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, attr := range n.attrs {
+		if err := write(attr.key, attr.value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, child := range n.children {
+		var value any
+		if child.list {
+			value = child.nodes
+			if child.nodes == nil {
+				value = []*astNode{}
+			}
+		} else if len(child.nodes) > 0 {
+			value = child.nodes[0]
+		}
+
+		if err := write(child.key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// endregion
+
+// region builders
+func (x *astPrinter) stmt(stmt Stmt) *astNode {
+	if stmt == nil {
+		return nil
+	}
+
+	_ = stmt.Accept(x)
+
+	return x.node
+}
+
+func (x *astPrinter) stmts(statements []Stmt) []*astNode {
+	nodes := make([]*astNode, 0, len(statements))
+	for _, stmt := range statements {
+		nodes = append(nodes, x.stmt(stmt))
+	}
+
+	return nodes
+}
+
+func (x *astPrinter) expr(expr Expr) *astNode {
+	if expr == nil {
+		return nil
+	}
+
+	node, _ := expr.Accept(x)
+
+	return node.(*astNode)
+}
+
+func (x *astPrinter) exprs(expressions []Expr) []*astNode {
+	nodes := make([]*astNode, 0, len(expressions))
+	for _, expr := range expressions {
+		nodes = append(nodes, x.expr(expr))
+	}
+
+	return nodes
+}
+
+func (x *astPrinter) lexemes(tokens []Token) []string {
+	names := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		names = append(names, token.Lexeme)
+	}
+
+	return names
+}
+
+// endregion
+
+// region Expression visitor methods
+func (x *astPrinter) VisitBinaryExpr(expr *Binary) (any, error) {
+	node := &astNode{kind: "Binary", head: expr.Operator.Lexeme, line: expr.Operator.Line}
+
+	return node.attr("operator", expr.Operator.Lexeme).
+		child("left", x.expr(expr.Left)).
+		child("right", x.expr(expr.Right)), nil
+}
+
+func (x *astPrinter) VisitGroupingExpr(expr *Grouping) (any, error) {
+	node := &astNode{kind: "Grouping", head: "group"}
+
+	return node.child("expression", x.expr(expr.Expression)), nil
+}
+
+func (x *astPrinter) VisitLiteralExpr(expr *Literal) (any, error) {
+	node := &astNode{kind: "Literal", head: x.literal(expr.Value), atom: true}
+
+	return node.attr("value", expr.Value), nil
+}
+
+func (x *astPrinter) VisitUnaryExpr(expr *Unary) (any, error) {
+	node := &astNode{kind: "Unary", head: expr.Operator.Lexeme, line: expr.Operator.Line}
+
+	return node.attr("operator", expr.Operator.Lexeme).
+		child("right", x.expr(expr.Right)), nil
+}
+
+func (x *astPrinter) VisitVariableExpr(expr *Variable) (any, error) {
+	node := &astNode{kind: "Variable", head: expr.Name.Lexeme, atom: true, line: expr.Name.Line}
+
+	return node.attr("name", expr.Name.Lexeme), nil
+}
+
+func (x *astPrinter) VisitAssignExpr(expr *Assign) (any, error) {
+	node := &astNode{kind: "Assign", head: expr.Operator.Lexeme + " " + expr.Name.Lexeme, line: expr.Name.Line}
+
+	return node.attr("name", expr.Name.Lexeme).
+		attr("operator", expr.Operator.Lexeme).
+		child("value", x.expr(expr.Value)), nil
+}
+
+func (x *astPrinter) VisitLogicalExpr(expr *Logical) (any, error) {
+	node := &astNode{kind: "Logical", head: expr.Operator.Lexeme, line: expr.Operator.Line}
+
+	return node.attr("operator", expr.Operator.Lexeme).
+		child("left", x.expr(expr.Left)).
+		child("right", x.expr(expr.Right)), nil
+}
+
+func (x *astPrinter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	node := &astNode{kind: "Conditional", head: "?:", line: expr.Question.Line}
+
+	return node.child("condition", x.expr(expr.Condition)).
+		child("then", x.expr(expr.Then)).
+		child("else", x.expr(expr.Else)), nil
+}
+
+func (x *astPrinter) VisitUpdateExpr(expr *UpdateExpr) (any, error) {
+	head := "postfix " + expr.Operator.Lexeme
+	if expr.Prefix {
+		head = "prefix " + expr.Operator.Lexeme
+	}
+
+	node := &astNode{kind: "Update", head: head, line: expr.Operator.Line}
+
+	return node.attr("operator", expr.Operator.Lexeme).
+		attr("prefix", expr.Prefix).
+		child("target", x.expr(expr.Target)), nil
+}
+
+func (x *astPrinter) VisitCallExpr(expr *Call) (any, error) {
+	node := &astNode{kind: "Call", head: "call", line: expr.Paren.Line}
+
+	return node.child("callee", x.expr(expr.Callee)).
+		childList("arguments", x.exprs(expr.Arguments)), nil
+}
+
+func (x *astPrinter) VisitSpawnExpr(expr *SpawnExpr) (any, error) {
+	node := &astNode{kind: "Spawn", head: "spawn", line: expr.Keyword.Line}
+
+	return node.child("call", x.expr(expr.Call)), nil
+}
+
+func (x *astPrinter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	node := &astNode{kind: "Index", head: "index", line: expr.Bracket.Line}
+
+	return node.child("object", x.expr(expr.Object)).
+		child("index", x.expr(expr.Index)), nil
+}
+
+func (x *astPrinter) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	node := &astNode{kind: "SetIndex", head: "set index", line: expr.Bracket.Line}
+
+	return node.attr("operator", expr.Operator.Lexeme).
+		child("object", x.expr(expr.Object)).
+		child("index", x.expr(expr.Index)).
+		child("value", x.expr(expr.Value)), nil
+}
+
+func (x *astPrinter) VisitMatchExpr(expr *MatchExpr) (any, error) {
+	node := &astNode{kind: "Match", head: "match", line: expr.Keyword.Line}
+
+	arms := make([]*astNode, 0, len(expr.Arms))
+	for _, arm := range expr.Arms {
+		patterns := make([]string, 0, len(arm.Patterns))
+		for _, pattern := range arm.Patterns {
+			patterns = append(patterns, patternString(pattern))
+		}
+
+		head := "case " + strings.Join(patterns, " | ")
+		if arm.Guard != nil {
+			head += " if"
+		}
+
+		armNode := &astNode{kind: "MatchArm", head: head, line: arm.Keyword.Line}
+
+		arms = append(arms, armNode.attr("patterns", patterns).
+			child("guard", x.expr(arm.Guard)).
+			child("body", x.expr(arm.Body)))
+	}
+
+	return node.child("value", x.expr(expr.Value)).
+		childList("arms", arms), nil
+}
+
+func (x *astPrinter) VisitGetExpr(expr *Get) (any, error) {
+	head := "get "
+	if expr.Optional {
+		head = "get? "
+	}
+
+	node := &astNode{kind: "Get", head: head + expr.Name.Lexeme, line: expr.Name.Line}
+
+	return node.attr("name", expr.Name.Lexeme).
+		attr("optional", expr.Optional).
+		child("object", x.expr(expr.Object)), nil
+}
+
+func (x *astPrinter) VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error) {
+	node := &astNode{kind: "OptionalChain", head: "?."}
+
+	return node.child("chain", x.expr(expr.Chain)), nil
+}
+
+func (x *astPrinter) VisitSetExpr(expr *Set) (any, error) {
+	node := &astNode{kind: "Set", head: "set " + expr.Name.Lexeme, line: expr.Name.Line}
+
+	return node.attr("name", expr.Name.Lexeme).
+		attr("operator", expr.Operator.Lexeme).
+		child("object", x.expr(expr.Object)).
+		child("value", x.expr(expr.Value)), nil
+}
+
+func (x *astPrinter) VisitThisExpr(expr *ThisExpr) (any, error) {
+	return &astNode{kind: "ThisExpr", head: "this", atom: true, line: expr.Keyword.Line}, nil
+}
+
+func (x *astPrinter) VisitSuperExpr(expr *SuperExpr) (any, error) {
+	node := &astNode{kind: "SuperExpr", head: "super." + expr.Method.Lexeme, atom: true, line: expr.Keyword.Line}
+
+	return node.attr("method", expr.Method.Lexeme), nil
+}
+
+// endregion
+
+// region Statement visitor methods
 //
-//	return builder.String()
-//}
+// StmtVisitor methods only return an error, so the built node is left in
+// x.node for x.stmt to pick up.
+func (x *astPrinter) VisitExpressionStmt(stmt *ExpressionStmt) error {
+	node := &astNode{kind: "ExpressionStmt", head: ";"}
+
+	x.node = node.child("expression", x.expr(stmt.Expression))
+
+	return nil
+}
+
+func (x *astPrinter) VisitPrintStmt(stmt *PrintStmt) error {
+	node := &astNode{kind: "PrintStmt", head: "print"}
+
+	x.node = node.child("expression", x.expr(stmt.Expression))
+
+	return nil
+}
+
+func (x *astPrinter) VisitVarStmt(stmt *VarStmt) error {
+	head := "var " + stmt.Name.Lexeme
+	if stmt.Constant {
+		head = "const " + stmt.Name.Lexeme
+	}
+
+	head += annotation(stmt.Type)
+
+	if stmt.Initializer != nil {
+		head += " ="
+	}
+
+	node := &astNode{kind: "VarStmt", head: head, line: stmt.Name.Line}
+	node.attr("name", stmt.Name.Lexeme)
+
+	if stmt.Type != nil {
+		node.attr("declaredType", stmt.Type.Name.Lexeme)
+	}
+
+	if stmt.Constant {
+		node.attr("constant", true)
+	}
+
+	x.node = node.child("initializer", x.expr(stmt.Initializer))
+
+	return nil
+}
+
+func (x *astPrinter) VisitBlockStmt(stmt *BlockStmt) error {
+	node := &astNode{kind: "BlockStmt", head: "block"}
+
+	x.node = node.childList("statements", x.stmts(stmt.Statements))
+
+	return nil
+}
+
+func (x *astPrinter) VisitIfStmt(stmt *IfStmt) error {
+	head := "if"
+	if stmt.ElseBranch != nil {
+		head = "if-else"
+	}
+
+	node := &astNode{kind: "IfStmt", head: head}
+
+	x.node = node.child("condition", x.expr(stmt.Condition)).
+		child("then", x.stmt(stmt.ThenBranch)).
+		child("else", x.stmt(stmt.ElseBranch))
+
+	return nil
+}
+
+func (x *astPrinter) VisitWhileStmt(stmt *WhileStmt) error {
+	node := &astNode{kind: "WhileStmt", head: "while"}
+
+	x.node = node.child("condition", x.expr(stmt.Condition)).
+		child("body", x.stmt(stmt.Body))
+
+	return nil
+}
+
+func (x *astPrinter) VisitForStmt(stmt *ForStmt) error {
+	node := &astNode{kind: "ForStmt", head: "for", line: stmt.Keyword.Line}
+
+	x.node = node.child("initializer", x.stmt(stmt.Initializer)).
+		child("condition", x.expr(stmt.Condition)).
+		child("increment", x.expr(stmt.Increment)).
+		child("body", x.stmt(stmt.Body))
+
+	return nil
+}
+
+func (x *astPrinter) VisitForInStmt(stmt *ForInStmt) error {
+	node := &astNode{kind: "ForInStmt", head: "for " + stmt.Name.Lexeme + " in", line: stmt.Keyword.Line}
+
+	x.node = node.attr("name", stmt.Name.Lexeme).
+		child("iterable", x.expr(stmt.Iterable)).
+		child("body", x.stmt(stmt.Body))
+
+	return nil
+}
+
+func (x *astPrinter) VisitFunctionStmt(stmt *FunctionStmt) error {
+	params := x.lexemes(stmt.Params)
+
+	// Parameter types are listed alongside params, "" where there's none.
+	annotated := make([]string, len(params))
+	types := make([]string, len(params))
+	typed := false
+	for i, param := range params {
+		annotated[i] = param
+
+		if paramType := stmt.ParamTypes[i]; paramType != nil {
+			annotated[i] += ":" + paramType.Name.Lexeme
+			types[i] = paramType.Name.Lexeme
+			typed = true
+		}
+	}
+
+	head := "fun " + stmt.Name.Lexeme + "(" + strings.Join(annotated, " ") + ")" + annotation(stmt.ReturnType)
+
+	node := &astNode{kind: "FunctionStmt", head: head, line: stmt.Name.Line}
+
+	node.attr("name", stmt.Name.Lexeme).attr("params", params)
+
+	if typed {
+		node.attr("paramTypes", types)
+	}
+
+	if stmt.ReturnType != nil {
+		node.attr("returnType", stmt.ReturnType.Name.Lexeme)
+	}
+
+	if stmt.IsGenerator {
+		node.attr("generator", true)
+	}
+
+	x.node = node.childList("body", x.stmts(stmt.Body))
+
+	return nil
+}
+
+func (x *astPrinter) VisitReturnStmt(stmt *ReturnStmt) error {
+	node := &astNode{kind: "ReturnStmt", head: "return", line: stmt.Keyword.Line}
+
+	x.node = node.child("value", x.expr(stmt.Value))
+
+	return nil
+}
+
+func (x *astPrinter) VisitYieldStmt(stmt *YieldStmt) error {
+	node := &astNode{kind: "YieldStmt", head: "yield", line: stmt.Keyword.Line}
+
+	x.node = node.child("value", x.expr(stmt.Value))
+
+	return nil
+}
+
+func (x *astPrinter) VisitClassStmt(stmt *ClassStmt) error {
+	head := "class " + stmt.Name.Lexeme
+
+	node := &astNode{kind: "ClassStmt", line: stmt.Name.Line}
+	node.attr("name", stmt.Name.Lexeme)
+
+	if stmt.Superclass != nil {
+		head += " < " + stmt.Superclass.Name.Lexeme
+		node.attr("superclass", stmt.Superclass.Name.Lexeme)
+	} else {
+		node.attr("superclass", nil)
+	}
+
+	if len(stmt.Traits) > 0 {
+		traits := make([]string, 0, len(stmt.Traits))
+		for _, trait := range stmt.Traits {
+			traits = append(traits, trait.Name.Lexeme)
+		}
+
+		head += " with " + strings.Join(traits, ", ")
+		node.attr("traits", traits)
+	}
+
+	node.head = head
+
+	if len(stmt.Fields) > 0 {
+		fields := make([]*astNode, 0, len(stmt.Fields))
+		for _, field := range stmt.Fields {
+			fields = append(fields, (&astNode{
+				kind: "FieldDecl",
+				head: field.Name.Lexeme + annotation(field.Type),
+				line: field.Name.Line,
+			}).attr("name", field.Name.Lexeme).attr("declaredType", field.Type.Name.Lexeme))
+		}
+
+		node.childList("fields", fields)
+	}
+
+	x.node = node.childList("methods", x.methods(stmt.Methods))
+
+	return nil
+}
+
+func (x *astPrinter) VisitTraitStmt(stmt *TraitStmt) error {
+	node := &astNode{kind: "TraitStmt", head: "trait " + stmt.Name.Lexeme, line: stmt.Name.Line}
+
+	x.node = node.attr("name", stmt.Name.Lexeme).
+		childList("methods", x.methods(stmt.Methods))
+
+	return nil
+}
+
+func (x *astPrinter) methods(methods []*FunctionStmt) []*astNode {
+	nodes := make([]*astNode, 0, len(methods))
+	for _, method := range methods {
+		nodes = append(nodes, x.stmt(method))
+	}
+
+	return nodes
+}
+
+// endregion
+
+// region renderers
+func (x *astPrinter) renderSExpr(program []*astNode) string {
+	var builder strings.Builder
+
+	for _, node := range program {
+		builder.WriteString(x.sexpr(node))
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+func (x *astPrinter) sexpr(node *astNode) string {
+	if node.atom {
+		return node.head
+	}
+
+	builder := strings.Builder{}
+
+	builder.WriteString("(")
+	builder.WriteString(node.head)
+
+	for _, child := range node.children {
+		for _, n := range child.nodes {
+			builder.WriteString(" ")
+			builder.WriteString(x.sexpr(n))
+		}
+	}
+
+	builder.WriteString(")")
+
+	return builder.String()
+}
+
+func (x *astPrinter) renderTree(program []*astNode) string {
+	var builder strings.Builder
+
+	for _, node := range program {
+		x.tree(&builder, node, "", "")
+	}
+
+	return builder.String()
+}
+
+func (x *astPrinter) tree(builder *strings.Builder, node *astNode, label string, prefix string) {
+	builder.WriteString(label)
+	builder.WriteString(node.kind)
+
+	for _, attr := range node.attrs {
+		builder.WriteString(" ")
+		builder.WriteString(attr.key)
+		builder.WriteString("=")
+		builder.WriteString(x.attrValue(attr.value))
+	}
+
+	builder.WriteString("\n")
+
+	type edge struct {
+		label string
+		node  *astNode
+	}
+
+	var edges []edge
+	for _, child := range node.children {
+		for i, n := range child.nodes {
+			key := child.key
+			if child.list {
+				key = fmt.Sprintf("%s[%d]", child.key, i)
+			}
+
+			edges = append(edges, edge{key + ": ", n})
+		}
+	}
+
+	for i, e := range edges {
+		connector, indent := "├── ", "│   "
+		if i == len(edges)-1 {
+			connector, indent = "└── ", "    "
+		}
+
+		builder.WriteString(prefix)
+		x.tree(builder, e.node, connector+e.label, prefix+indent)
+	}
+}
+
+func (x *astPrinter) renderJSON(program []*astNode) (string, error) {
+	var builder strings.Builder
+
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(program); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+func (x *astPrinter) renderDot(program []*astNode) string {
+	var builder strings.Builder
+
+	builder.WriteString("digraph AST {\n")
+	builder.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	builder.WriteString("  n0 [label=\"Program\"];\n")
+
+	id := 0
+	var walk func(node *astNode) int
+	walk = func(node *astNode) int {
+		id++
+		nodeID := id
+
+		label := node.kind
+		for _, attr := range node.attrs {
+			label += "\n" + attr.key + "=" + x.attrValue(attr.value)
+		}
+
+		fmt.Fprintf(&builder, "  n%d [label=%s];\n", nodeID, strconv.Quote(label))
+
+		for _, child := range node.children {
+			for i, n := range child.nodes {
+				key := child.key
+				if child.list {
+					key = fmt.Sprintf("%s[%d]", child.key, i)
+				}
+
+				childID := walk(n)
+				fmt.Fprintf(&builder, "  n%d -> n%d [label=%s];\n", nodeID, childID, strconv.Quote(key))
+			}
+		}
+
+		return nodeID
+	}
+
+	for _, node := range program {
+		fmt.Fprintf(&builder, "  n0 -> n%d;\n", walk(node))
+	}
+
+	builder.WriteString("}\n")
+
+	return builder.String()
+}
+
+func (x *astPrinter) literal(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+func (x *astPrinter) attrValue(value any) string {
+	if names, ok := value.([]string); ok {
+		return "[" + strings.Join(names, ", ") + "]"
+	}
+
+	return x.literal(value)
+}
+
+// endregion
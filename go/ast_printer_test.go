@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPrintAst renders testdata/ast/program.lox in every format and checks
+// the output against the file named after the format.
+func TestPrintAst(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("testdata", "ast", "program.lox"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements, err := parse(string(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range astFormats {
+		expected, err := os.ReadFile(filepath.Join("testdata", "ast", "program."+format))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := (&astPrinter{}).Print(statements, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output != string(expected) {
+			t.Errorf("%s: expected\n%s\ngot\n%s", format, expected, output)
+		}
+	}
+}
+
+func TestPrintAstRejectsUnknownFormat(t *testing.T) {
+	if _, err := (&astPrinter{}).Print(nil, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -1,5 +1,7 @@
 package main
 
+import "sync"
+
 type Callable interface {
 	Arity() int
 	String() string
@@ -32,6 +34,10 @@ func (f *FunctionImpl) Call(interpreter *Interpreter, arguments []any) (retVal a
 		}
 	}()
 
+	if err = interpreter.allocate(environmentSize + len(arguments)*variableSize); err != nil {
+		return nil, err
+	}
+
 	environment := &Environment{
 		values:    map[string]any{},
 		enclosing: f.closure,
@@ -41,6 +47,15 @@ func (f *FunctionImpl) Call(interpreter *Interpreter, arguments []any) (retVal a
 		environment.Define(f.declaration.Params[i].Lexeme, arguments[i])
 	}
 
+	if f.declaration.IsGenerator {
+		return newGenerator(f, environment), nil
+	}
+
+	if interpreter.debugger != nil {
+		interpreter.debugger.enterFunction(f, environment)
+		defer interpreter.debugger.exitFunction(f)
+	}
+
 	err = interpreter.executeBlock(f.declaration.Body, environment)
 	if err != nil {
 		return nil, err
@@ -79,6 +94,7 @@ type ClassImpl struct {
 	name       string
 	methods    map[string]*FunctionImpl
 	superclass *ClassImpl
+	traits     []*TraitImpl
 }
 
 func (c *ClassImpl) Arity() int {
@@ -91,6 +107,10 @@ func (c *ClassImpl) Arity() int {
 }
 
 func (c *ClassImpl) Call(interpreter *Interpreter, args []any) (any, error) {
+	if err := interpreter.allocate(instanceSize); err != nil {
+		return nil, err
+	}
+
 	instance := &InstanceImpl{klass: c}
 
 	initializer := c.FindMethod("init")
@@ -108,6 +128,17 @@ func (c *ClassImpl) String() string {
 	return c.name
 }
 
+// isSubclassOf reports whether c is class or inherits from it.
+func (c *ClassImpl) isSubclassOf(class *ClassImpl) bool {
+	for ; c != nil; c = c.superclass {
+		if c == class {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *ClassImpl) FindMethod(name string) *FunctionImpl {
 	if method, ok := c.methods[name]; ok {
 		return method
@@ -122,15 +153,13 @@ func (c *ClassImpl) FindMethod(name string) *FunctionImpl {
 
 type InstanceImpl struct {
 	klass  *ClassImpl
+	mutex  sync.RWMutex // guards fields, which tasks may share
 	fields map[string]any
+	frozen bool // set by freeze(), after which fields can't be set
 }
 
 func (x *InstanceImpl) Get(name Token) (any, error) {
-	if x.fields == nil {
-		x.fields = make(map[string]any)
-	}
-
-	if value, ok := x.fields[name.Lexeme]; ok {
+	if value, ok := x.field(name.Lexeme); ok {
 		return value, nil
 	}
 
@@ -141,12 +170,59 @@ func (x *InstanceImpl) Get(name Token) (any, error) {
 	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
 }
 
-func (x *InstanceImpl) Set(name Token, value any) {
+func (x *InstanceImpl) Set(name Token, value any) error {
+	if !x.setField(name.Lexeme, value) {
+		return RuntimeError{"can't set property '" + name.Lexeme + "' of a frozen instance", name}
+	}
+
+	return nil
+}
+
+// setField sets a field unless x is frozen, and reports whether it did.
+func (x *InstanceImpl) setField(name string, value any) bool {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if x.frozen {
+		return false
+	}
+
 	if x.fields == nil {
 		x.fields = make(map[string]any)
 	}
 
-	x.fields[name.Lexeme] = value
+	x.fields[name] = value
+
+	return true
+}
+
+func (x *InstanceImpl) freeze() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.frozen = true
+}
+
+func (x *InstanceImpl) field(name string) (any, bool) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	value, ok := x.fields[name]
+
+	return value, ok
+}
+
+// snapshot returns a copy of the fields of x.
+func (x *InstanceImpl) snapshot() map[string]any {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	fields := make(map[string]any, len(x.fields))
+	for name, value := range x.fields {
+		fields[name] = value
+	}
+
+	return fields
 }
 
 func (x *InstanceImpl) String() string {
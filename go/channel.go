@@ -0,0 +1,292 @@
+package main
+
+// ChannelImpl passes values between tasks, as Go channels do: sending waits
+// for a receiver unless the channel has room in its buffer, and receiving
+// waits for a value. Its state is guarded by the blockMutex of the budget of
+// the program that made it, which also counts the tasks waiting on it.
+type ChannelImpl struct {
+	budget    *budget
+	buffer    []any
+	capacity  int
+	closed    bool
+	receivers []*waiter
+	senders   []*waiter // each holding the value it sends
+}
+
+// Get returns the method of x called name, bound to x.
+func (x *ChannelImpl) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "send":
+		return &nativeFunction{"send", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			return nil, x.send(interpreter, arguments[0])
+		}}, nil
+	case "receive":
+		return &nativeFunction{"receive", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			value, _, err := x.receive(interpreter)
+
+			return value, err
+		}}, nil
+	case "close":
+		return &nativeFunction{"close", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			return nil, x.close(interpreter)
+		}}, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+// lock locks the state of x, which only tasks of the program that made it
+// may use.
+func (x *ChannelImpl) lock(interpreter *Interpreter) error {
+	if interpreter.budget != x.budget {
+		return nativeErrorf("channel belongs to another program")
+	}
+
+	x.budget.blockMutex.Lock()
+
+	return nil
+}
+
+// receive waits for a value and reports false instead once x is closed and
+// drained.
+func (x *ChannelImpl) receive(interpreter *Interpreter) (any, bool, error) {
+	if err := x.lock(interpreter); err != nil {
+		return nil, false, err
+	}
+
+	if value, ok, ready := x.tryReceive(); ready {
+		x.budget.blockMutex.Unlock()
+
+		return value, ok, nil
+	}
+
+	waiter := newWaiter()
+	x.receivers = append(x.receivers, waiter)
+	x.budget.park()
+	x.budget.blockMutex.Unlock()
+
+	if err := x.budget.wait(waiter); err != nil {
+		return nil, false, err
+	}
+
+	return waiter.value, waiter.ok, nil
+}
+
+// tryReceive takes a value from the buffer or a waiting sender, and reports
+// whether it could or x is closed and drained. It must be called with x
+// locked.
+func (x *ChannelImpl) tryReceive() (value any, ok bool, ready bool) {
+	if len(x.buffer) > 0 {
+		value, x.buffer = x.buffer[0], x.buffer[1:]
+
+		if sender := nextWaiter(&x.senders); sender != nil {
+			x.buffer = append(x.buffer, sender.value)
+			sender.ok = true
+			x.budget.complete(sender)
+		}
+
+		return value, true, true
+	}
+
+	if sender := nextWaiter(&x.senders); sender != nil {
+		sender.ok = true
+		x.budget.complete(sender)
+
+		return sender.value, true, true
+	}
+
+	return nil, false, x.closed
+}
+
+func (x *ChannelImpl) send(interpreter *Interpreter, value any) error {
+	if err := x.lock(interpreter); err != nil {
+		return err
+	}
+
+	if x.closed {
+		x.budget.blockMutex.Unlock()
+
+		return nativeErrorf("send on closed channel")
+	}
+
+	if receiver := nextWaiter(&x.receivers); receiver != nil {
+		receiver.channel, receiver.value, receiver.ok = x, value, true
+		x.budget.complete(receiver)
+		x.budget.blockMutex.Unlock()
+
+		return nil
+	}
+
+	if len(x.buffer) < x.capacity {
+		x.buffer = append(x.buffer, value)
+		x.budget.blockMutex.Unlock()
+
+		return nil
+	}
+
+	waiter := newWaiter()
+	waiter.value = value
+	x.senders = append(x.senders, waiter)
+	x.budget.park()
+	x.budget.blockMutex.Unlock()
+
+	if err := x.budget.wait(waiter); err != nil {
+		return err
+	}
+
+	if !waiter.ok {
+		return nativeErrorf("send on closed channel")
+	}
+
+	return nil
+}
+
+// close closes x, waking the tasks waiting on it: receivers get nil and
+// senders an error.
+func (x *ChannelImpl) close(interpreter *Interpreter) error {
+	if err := x.lock(interpreter); err != nil {
+		return err
+	}
+
+	defer x.budget.blockMutex.Unlock()
+
+	if x.closed {
+		return nativeErrorf("channel is already closed")
+	}
+
+	x.closed = true
+
+	for _, receiver := range x.receivers {
+		receiver.channel, receiver.value, receiver.ok = x, nil, false
+		x.budget.complete(receiver)
+	}
+
+	for _, sender := range x.senders {
+		x.budget.complete(sender)
+	}
+
+	x.receivers, x.senders = nil, nil
+
+	return nil
+}
+
+// nextWaiter removes the first waiter in queue still waiting and returns it,
+// or nil if there is none. Waiters that gave up, or that a select left for
+// another channel, are dropped on the way.
+func nextWaiter(queue *[]*waiter) *waiter {
+	for len(*queue) > 0 {
+		waiter := (*queue)[0]
+		*queue = (*queue)[1:]
+
+		if !waiter.done {
+			return waiter
+		}
+	}
+
+	return nil
+}
+
+func (x *ChannelImpl) String() string {
+	return "<channel>"
+}
+
+// channel([capacity]) makes a channel buffering up to capacity values, by
+// default none.
+func funChannel(interpreter *Interpreter, arguments []any) (any, error) {
+	if len(arguments) > 1 {
+		return nil, nativeErrorf("'channel' expects at most 1 argument but got %d", len(arguments))
+	}
+
+	capacity := 0
+	if len(arguments) == 1 {
+		var err error
+		if capacity, err = integerArgument("channel", arguments, 0); err != nil {
+			return nil, err
+		}
+
+		if capacity < 0 {
+			return nil, nativeErrorf("channel capacity must not be negative")
+		}
+	}
+
+	if err := interpreter.allocate(channelSize + capacity*valueSize); err != nil {
+		return nil, err
+	}
+
+	return &ChannelImpl{budget: interpreter.budget, capacity: capacity}, nil
+}
+
+// select(channels...) waits until one of channels has a value and returns the
+// list [channel, value]. Channels that are closed and drained are left out,
+// and once all of them are, select returns nil.
+func funSelect(interpreter *Interpreter, arguments []any) (any, error) {
+	channels := make([]*ChannelImpl, 0, len(arguments))
+
+	for i, argument := range arguments {
+		channel, ok := argument.(*ChannelImpl)
+		if !ok {
+			return nil, nativeErrorf("argument %d to 'select' must be a channel", i+1)
+		}
+
+		if channel.budget != interpreter.budget {
+			return nil, nativeErrorf("channel belongs to another program")
+		}
+
+		channels = append(channels, channel)
+	}
+
+	budget := interpreter.budget
+
+	for {
+		budget.blockMutex.Lock()
+
+		open := channels[:0:0]
+
+		for _, channel := range channels {
+			value, ok, ready := channel.tryReceive()
+			if ok {
+				budget.blockMutex.Unlock()
+
+				if err := interpreter.allocateList(2); err != nil {
+					return nil, err
+				}
+
+				return newList([]any{channel, value}), nil
+			}
+
+			if !ready {
+				open = append(open, channel)
+			}
+		}
+
+		if len(open) == 0 {
+			budget.blockMutex.Unlock()
+
+			return nil, nil
+		}
+
+		// The waiter is completed by the first of the channels to get a
+		// value or be closed; the others skip it from then on.
+		waiter := newWaiter()
+		for _, channel := range open {
+			channel.receivers = append(channel.receivers, waiter)
+		}
+
+		budget.park()
+		budget.blockMutex.Unlock()
+
+		if err := budget.wait(waiter); err != nil {
+			return nil, err
+		}
+
+		if waiter.ok {
+			if err := interpreter.allocateList(2); err != nil {
+				return nil, err
+			}
+
+			return newList([]any{waiter.channel, waiter.value}), nil
+		}
+
+		channels = open
+	}
+}
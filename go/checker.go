@@ -0,0 +1,1011 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// region Static types
+
+// staticType is what the checker knows about the values an expression can
+// have. Any stands for values it knows nothing about, which every check lets
+// through: that's what keeps unannotated code working as before.
+type staticType interface {
+	String() string
+}
+
+type basicType string
+
+const (
+	typeAny    basicType = "Any"
+	typeNumber basicType = "Number"
+	typeString basicType = "String"
+	typeBool   basicType = "Bool"
+	typeNil    basicType = "Nil"
+	typeList   basicType = "List"
+	typeMap    basicType = "Map"
+)
+
+func (t basicType) String() string {
+	return string(t)
+}
+
+// signature is the type of functions, methods and natives.
+type signature struct {
+	name      string
+	params    []staticType
+	variadic  bool // any number of arguments of any type
+	result    staticType
+	generator bool // calls return a generator rather than result
+}
+
+func (t *signature) String() string {
+	return "Function"
+}
+
+// anyFunction is the type annotations name Function.
+var anyFunction = &signature{variadic: true, result: typeAny}
+
+// staticClass is the type of the instances of a class: its declared fields
+// and its methods. Fields that aren't declared, and methods mixed in from
+// traits, are Any.
+type staticClass struct {
+	name       string
+	superclass *staticClass
+	fields     map[string]staticType
+	methods    map[string]*signature
+	traits     bool // mixes in traits, whose methods aren't known
+}
+
+func (t *staticClass) String() string {
+	return t.name
+}
+
+func (t *staticClass) field(name string) (staticType, bool) {
+	for ; t != nil; t = t.superclass {
+		if field, ok := t.fields[name]; ok {
+			return field, true
+		}
+	}
+
+	return nil, false
+}
+
+// method returns the signature of the method called name, or nil if there
+// is none or a trait may supply it.
+func (t *staticClass) method(name string) *signature {
+	for ; t != nil; t = t.superclass {
+		if method, ok := t.methods[name]; ok {
+			return method
+		}
+
+		if t.traits {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// mixesTraits reports whether t or one of its superclasses mixes in traits.
+func (t *staticClass) mixesTraits() bool {
+	for ; t != nil; t = t.superclass {
+		if t.traits {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *staticClass) isSubclassOf(class *staticClass) bool {
+	for ; t != nil; t = t.superclass {
+		if t == class {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classValue is the type of a class itself, which is called to make
+// instances.
+type classValue struct {
+	class *staticClass
+}
+
+func (t classValue) String() string {
+	return "class " + t.class.name
+}
+
+var annotationTypes = map[string]staticType{
+	"Any":      typeAny,
+	"Number":   typeNumber,
+	"String":   typeString,
+	"Bool":     typeBool,
+	"Nil":      typeNil,
+	"List":     typeList,
+	"Map":      typeMap,
+	"Function": anyFunction,
+}
+
+// assignable reports whether a value of type from may be stored where type
+// to is declared. nil fits instances, lists, maps and functions.
+func assignable(to, from staticType) bool {
+	if to == typeAny || from == typeAny || to == from {
+		return true
+	}
+
+	switch to := to.(type) {
+	case *staticClass:
+		if from, ok := from.(*staticClass); ok {
+			return from.isSubclassOf(to)
+		}
+	case *signature:
+		switch from.(type) {
+		case *signature, classValue:
+			return true
+		}
+	}
+
+	return from == typeNil && to != typeNumber && to != typeString && to != typeBool
+}
+
+// dynamic reports whether values of type t might overload operators or be
+// anything at all, so operations on them can't be checked.
+func dynamic(t staticType) bool {
+	_, ok := t.(*staticClass)
+
+	return ok || t == typeAny
+}
+
+// endregion
+
+// Checker is an optional pass run after the Resolver that infers the types
+// of expressions from literals, operators and type annotations, and reports
+// the operations and assignments that would fail or break an annotation.
+// Unannotated parameters, and unannotated variables assigned after their
+// declaration, are Any, so the checker only reports what it can be sure of;
+// the interpreter ignores annotations altogether.
+type Checker struct {
+	globals     map[string]binding
+	scopes      []map[string]binding
+	classes     map[*ClassStmt]*staticClass
+	functions   map[*FunctionStmt]*signature
+	assignments *assignments
+	function    *signature   // being checked, nil at the top level
+	generator   bool         // the function being checked yields
+	class       *staticClass // whose methods are being checked
+	errors      []error
+}
+
+// assignments records, as a resolutionListener, the variables assigned
+// after their declaration: local ones by where they are declared, globals by
+// name. Declaring a global again counts as assigning it.
+type assignments struct {
+	locals          map[[2]int]bool
+	globals         map[string]bool
+	declaredGlobals map[string]bool
+}
+
+func (x *assignments) declared(name Token, _ Stmt, local bool) {
+	if local {
+		return
+	}
+
+	if x.declaredGlobals[name.Lexeme] {
+		x.globals[name.Lexeme] = true
+	}
+
+	x.declaredGlobals[name.Lexeme] = true
+}
+
+func (x *assignments) referenced(Token, Token, bool) {}
+
+func (x *assignments) assigned(name Token, declaration Token, local bool) {
+	if local {
+		x.locals[[2]int{declaration.Line, declaration.Column}] = true
+	} else {
+		x.globals[name.Lexeme] = true
+	}
+}
+
+// reassigned reports whether the variable declared by name is assigned to.
+func (x *assignments) reassigned(name Token, local bool) bool {
+	if local {
+		return x.locals[[2]int{name.Line, name.Column}]
+	}
+
+	return x.globals[name.Lexeme]
+}
+
+// binding is the type of a variable, which only constrains assignments when
+// it was declared with an annotation.
+type binding struct {
+	t        staticType
+	declared bool
+}
+
+func NewChecker() *Checker {
+	globals := make(map[string]binding)
+
+	for name, value := range (&Interpreter{}).Init().globals.snapshot() {
+		if fn, ok := value.(Callable); ok {
+			globals[name] = binding{t: nativeSignature(name, fn.Arity())}
+		}
+	}
+
+	return &Checker{
+		globals:   globals,
+		classes:   make(map[*ClassStmt]*staticClass),
+		functions: make(map[*FunctionStmt]*signature),
+		assignments: &assignments{
+			locals:          make(map[[2]int]bool),
+			globals:         make(map[string]bool),
+			declaredGlobals: make(map[string]bool),
+		},
+	}
+}
+
+func nativeSignature(name string, arity int) *signature {
+	if arity < 0 {
+		return &signature{name: name, variadic: true, result: typeAny}
+	}
+
+	params := make([]staticType, arity)
+	for i := range params {
+		params[i] = typeAny
+	}
+
+	return &signature{name: name, params: params, result: typeAny}
+}
+
+// Check returns the type errors in statements, which must have been resolved,
+// ordered by line.
+func (x *Checker) Check(statements []Stmt) []error {
+	// Resolving again only serves to find the assignments, so it can't fail.
+	resolver := NewResolver()
+	resolver.listen(x.assignments)
+	_, _ = resolver.Resolve(statements)
+
+	x.hoist(statements)
+
+	for _, stmt := range statements {
+		x.stmt(stmt)
+	}
+
+	sort.SliceStable(x.errors, func(i, j int) bool {
+		return x.errors[i].(*CompileError).Line < x.errors[j].(*CompileError).Line
+	})
+
+	return x.errors
+}
+
+// hoist declares the classes and functions of the top level before checking
+// any code, since functions may refer to those declared after them.
+func (x *Checker) hoist(statements []Stmt) {
+	for _, stmt := range statements {
+		if stmt, ok := stmt.(*ClassStmt); ok {
+			x.classes[stmt] = &staticClass{name: stmt.Name.Lexeme}
+			x.declare(stmt.Name, binding{t: classValue{x.classes[stmt]}})
+		}
+	}
+
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ClassStmt:
+			x.defineClass(stmt, x.classes[stmt])
+		case *FunctionStmt:
+			x.functions[stmt] = x.signature(stmt)
+			x.declare(stmt.Name, binding{t: x.functions[stmt]})
+		}
+	}
+}
+
+// region Statement visitor methods
+func (x *Checker) VisitExpressionStmt(stmt *ExpressionStmt) error {
+	x.expr(stmt.Expression)
+
+	return nil
+}
+
+func (x *Checker) VisitPrintStmt(stmt *PrintStmt) error {
+	x.expr(stmt.Expression)
+
+	return nil
+}
+
+func (x *Checker) VisitVarStmt(stmt *VarStmt) error {
+	value := staticType(typeNil)
+	if stmt.Initializer != nil {
+		value = x.expr(stmt.Initializer)
+	}
+
+	switch {
+	case stmt.Type != nil:
+		declared := x.annotation(stmt.Type)
+
+		if stmt.Initializer != nil && !assignable(declared, value) {
+			x.errorf(stmt.Name, "can't assign %s to '%s' of type %s", value, stmt.Name.Lexeme, declared)
+		}
+
+		x.declare(stmt.Name, binding{t: declared, declared: true})
+	case stmt.Constant || !x.assignments.reassigned(stmt.Name, len(x.scopes) > 0):
+		x.declare(stmt.Name, binding{t: value})
+	default:
+		// A variable assigned later may hold anything, so it's only known to
+		// hold its initializer's type if that's declared.
+		x.declare(stmt.Name, binding{t: typeAny})
+	}
+
+	return nil
+}
+
+func (x *Checker) VisitBlockStmt(stmt *BlockStmt) error {
+	x.beginScope()
+	x.stmts(stmt.Statements)
+	x.endScope()
+
+	return nil
+}
+
+func (x *Checker) VisitIfStmt(stmt *IfStmt) error {
+	x.expr(stmt.Condition)
+	x.stmt(stmt.ThenBranch)
+
+	if stmt.ElseBranch != nil {
+		x.stmt(stmt.ElseBranch)
+	}
+
+	return nil
+}
+
+func (x *Checker) VisitWhileStmt(stmt *WhileStmt) error {
+	x.expr(stmt.Condition)
+	x.stmt(stmt.Body)
+
+	return nil
+}
+
+func (x *Checker) VisitForStmt(stmt *ForStmt) error {
+	x.beginScope()
+
+	if stmt.Initializer != nil {
+		x.stmt(stmt.Initializer)
+	}
+
+	if stmt.Condition != nil {
+		x.expr(stmt.Condition)
+	}
+
+	if stmt.Increment != nil {
+		x.expr(stmt.Increment)
+	}
+
+	x.stmt(stmt.Body)
+	x.endScope()
+
+	return nil
+}
+
+func (x *Checker) VisitForInStmt(stmt *ForInStmt) error {
+	x.expr(stmt.Iterable)
+
+	x.beginScope()
+	x.declare(stmt.Name, binding{t: typeAny})
+	x.stmt(stmt.Body)
+	x.endScope()
+
+	return nil
+}
+
+func (x *Checker) VisitFunctionStmt(stmt *FunctionStmt) error {
+	fn, ok := x.functions[stmt]
+	if !ok {
+		fn = x.signature(stmt)
+	}
+
+	x.declare(stmt.Name, binding{t: fn})
+	x.checkFunction(stmt, fn)
+
+	return nil
+}
+
+func (x *Checker) VisitReturnStmt(stmt *ReturnStmt) error {
+	value := staticType(typeNil)
+	if stmt.Value != nil {
+		value = x.expr(stmt.Value)
+	}
+
+	if x.function != nil && !x.generator && !assignable(x.function.result, value) {
+		x.errorf(stmt.Keyword, "'%s' must return %s, got %s", x.function.name, x.function.result, value)
+	}
+
+	return nil
+}
+
+func (x *Checker) VisitYieldStmt(stmt *YieldStmt) error {
+	if stmt.Value != nil {
+		x.expr(stmt.Value)
+	}
+
+	return nil
+}
+
+func (x *Checker) VisitClassStmt(stmt *ClassStmt) error {
+	class, ok := x.classes[stmt]
+	if !ok {
+		class = &staticClass{name: stmt.Name.Lexeme}
+		x.declare(stmt.Name, binding{t: classValue{class}})
+		x.defineClass(stmt, class)
+	}
+
+	enclosing := x.class
+	x.class = class
+
+	for _, method := range stmt.Methods {
+		x.checkFunction(method, class.methods[method.Name.Lexeme])
+	}
+
+	x.class = enclosing
+
+	return nil
+}
+
+func (x *Checker) VisitTraitStmt(stmt *TraitStmt) error {
+	x.declare(stmt.Name, binding{t: typeAny})
+
+	// Trait methods don't know the class they'll be mixed into, so 'this'
+	// is Any in them.
+	enclosing := x.class
+	x.class = nil
+
+	for _, method := range stmt.Methods {
+		x.checkFunction(method, x.signature(method))
+	}
+
+	x.class = enclosing
+
+	return nil
+}
+
+// endregion
+
+// region Expression visitor methods
+func (x *Checker) VisitBinaryExpr(expr *Binary) (any, error) {
+	return x.binary(expr.Operator, x.expr(expr.Left), x.expr(expr.Right)), nil
+}
+
+// binary returns the type of applying an operator to operands of types left
+// and right, reporting operands it can't apply to.
+func (x *Checker) binary(operator Token, left staticType, right staticType) staticType {
+	switch operator.Type {
+	case Plus:
+		switch {
+		case left == typeNumber && right == typeNumber:
+			return typeNumber
+		case left == typeString && right == typeString:
+			return typeString
+		case dynamic(left) || dynamic(right):
+			return typeAny
+		}
+
+		x.errorf(operator, "operands must be two numbers or two strings")
+	case Minus, Slash, Star, Percent, Greater, GreaterEqual, Less, LessEqual:
+		result := staticType(typeNumber)
+		if operator.Type != Minus && operator.Type != Slash && operator.Type != Star && operator.Type != Percent {
+			result = typeBool
+		}
+
+		switch {
+		case left == typeNumber && right == typeNumber:
+			return result
+		case dynamic(left) || dynamic(right):
+			return typeAny
+		}
+
+		x.errorf(operator, "operands must be numbers")
+	case EqualEqual, BangEqual, Is:
+		return typeBool
+	}
+
+	return typeAny
+}
+
+// compound returns the type of a compound assignment, increment or
+// decrement of a target of type current by an operand of type operand.
+func (x *Checker) compound(operator Token, current staticType, operand staticType) staticType {
+	operator.Type = compoundOperators[operator.Type]
+
+	return x.binary(operator, current, operand)
+}
+
+func (x *Checker) VisitGroupingExpr(expr *Grouping) (any, error) {
+	return x.expr(expr.Expression), nil
+}
+
+func (x *Checker) VisitLiteralExpr(expr *Literal) (any, error) {
+	switch expr.Value.(type) {
+	case float64:
+		return typeNumber, nil
+	case string:
+		return typeString, nil
+	case bool:
+		return typeBool, nil
+	case nil:
+		return typeNil, nil
+	}
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitUnaryExpr(expr *Unary) (any, error) {
+	right := x.expr(expr.Right)
+
+	if expr.Operator.Type == Bang {
+		return typeBool, nil
+	}
+
+	switch {
+	case right == typeNumber:
+		return typeNumber, nil
+	case dynamic(right):
+		return typeAny, nil
+	}
+
+	x.errorf(expr.Operator, "operand must be a number")
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitVariableExpr(expr *Variable) (any, error) {
+	return x.lookup(expr.Name).t, nil
+}
+
+func (x *Checker) VisitAssignExpr(expr *Assign) (any, error) {
+	value := x.expr(expr.Value)
+	variable := x.lookup(expr.Name)
+
+	if expr.Operator.Type != Equal {
+		value = x.compound(expr.Operator, variable.t, value)
+	}
+
+	if variable.declared && !assignable(variable.t, value) {
+		x.errorf(expr.Name, "can't assign %s to '%s' of type %s", value, expr.Name.Lexeme, variable.t)
+	}
+
+	return value, nil
+}
+
+func (x *Checker) VisitLogicalExpr(expr *Logical) (any, error) {
+	left := x.expr(expr.Left)
+	right := x.expr(expr.Right)
+
+	if left == right {
+		return left, nil
+	}
+
+	if expr.Operator.Type == QuestionQuestion && left == typeNil {
+		return right, nil
+	}
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitCallExpr(expr *Call) (any, error) {
+	callee := x.expr(expr.Callee)
+
+	arguments := make([]staticType, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		arguments = append(arguments, x.expr(argument))
+	}
+
+	switch callee := callee.(type) {
+	case *signature:
+		x.checkArguments(expr, callee, arguments)
+
+		if callee.generator {
+			return typeAny, nil
+		}
+
+		return callee.result, nil
+	case classValue:
+		initializer := callee.class.method("init")
+		if initializer == nil {
+			if callee.class.mixesTraits() {
+				return callee.class, nil
+			}
+
+			initializer = &signature{name: callee.class.name, result: typeAny}
+		}
+
+		x.checkArguments(expr, initializer, arguments)
+
+		return callee.class, nil
+	case basicType:
+		if callee != typeAny {
+			x.errorf(expr.Paren, "can only call functions and classes")
+		}
+	}
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitGetExpr(expr *Get) (any, error) {
+	object := x.expr(expr.Object)
+
+	switch object {
+	case typeNil:
+		if expr.Optional {
+			return typeAny, nil
+		}
+
+		x.errorf(expr.Name, "only instances have properties")
+	case typeNumber, typeBool:
+		x.errorf(expr.Name, "only instances have properties")
+	}
+
+	if class, ok := object.(*staticClass); ok {
+		if field, ok := class.field(expr.Name.Lexeme); ok {
+			return field, nil
+		}
+
+		if method := class.method(expr.Name.Lexeme); method != nil {
+			return method, nil
+		}
+	}
+
+	return typeAny, nil
+}
+
+// VisitOptionalChainExpr gives chains Any, since they evaluate to nil if they
+// stop early.
+func (x *Checker) VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error) {
+	x.expr(expr.Chain)
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitSetExpr(expr *Set) (any, error) {
+	object := x.expr(expr.Object)
+	value := x.expr(expr.Value)
+
+	field, declared := staticType(typeAny), false
+
+	switch object := object.(type) {
+	case *staticClass:
+		if t, ok := object.field(expr.Name.Lexeme); ok {
+			field, declared = t, true
+		}
+	default:
+		if object != typeAny {
+			x.errorf(expr.Name, "only instances have fields")
+		}
+	}
+
+	if expr.Operator.Type != Equal {
+		value = x.compound(expr.Operator, field, value)
+	}
+
+	if declared && !assignable(field, value) {
+		x.errorf(expr.Name, "can't assign %s to field '%s' of type %s", value, expr.Name.Lexeme, field)
+	}
+
+	return value, nil
+}
+
+func (x *Checker) VisitThisExpr(expr *ThisExpr) (any, error) {
+	if x.class == nil {
+		return typeAny, nil
+	}
+
+	return x.class, nil
+}
+
+func (x *Checker) VisitSuperExpr(expr *SuperExpr) (any, error) {
+	if x.class != nil {
+		if method := x.class.superclass.method(expr.Method.Lexeme); method != nil {
+			return method, nil
+		}
+	}
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitMatchExpr(expr *MatchExpr) (any, error) {
+	x.expr(expr.Value)
+
+	var result staticType
+
+	for _, arm := range expr.Arms {
+		x.beginScope()
+
+		for _, pattern := range arm.Patterns {
+			x.declarePattern(pattern)
+		}
+
+		if arm.Guard != nil {
+			x.expr(arm.Guard)
+		}
+
+		body := x.expr(arm.Body)
+
+		x.endScope()
+
+		if result == nil {
+			result = body
+		} else if result != body {
+			result = typeAny
+		}
+	}
+
+	if result == nil {
+		return typeAny, nil
+	}
+
+	return result, nil
+}
+
+func (x *Checker) VisitSpawnExpr(expr *SpawnExpr) (any, error) {
+	x.expr(expr.Call)
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	x.expr(expr.Object)
+	x.expr(expr.Index)
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	x.expr(expr.Object)
+	x.expr(expr.Index)
+
+	value := x.expr(expr.Value)
+	if expr.Operator.Type != Equal {
+		return x.compound(expr.Operator, typeAny, value), nil
+	}
+
+	return value, nil
+}
+
+func (x *Checker) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	x.expr(expr.Condition)
+
+	then := x.expr(expr.Then)
+	otherwise := x.expr(expr.Else)
+
+	if then == otherwise {
+		return then, nil
+	}
+
+	return typeAny, nil
+}
+
+func (x *Checker) VisitUpdateExpr(expr *UpdateExpr) (any, error) {
+	target := x.expr(expr.Target)
+
+	switch {
+	case target == typeNumber:
+		return typeNumber, nil
+	case dynamic(target):
+		return typeAny, nil
+	}
+
+	x.errorf(expr.Operator, "operand must be a number")
+
+	return typeAny, nil
+}
+
+// endregion
+
+// region helpers
+func (x *Checker) stmt(stmt Stmt) {
+	_ = stmt.Accept(x)
+}
+
+func (x *Checker) stmts(statements []Stmt) {
+	for _, stmt := range statements {
+		x.stmt(stmt)
+	}
+}
+
+func (x *Checker) expr(expr Expr) staticType {
+	t, _ := expr.Accept(x)
+
+	return t.(staticType)
+}
+
+func (x *Checker) errorf(token Token, format string, args ...any) {
+	x.errors = append(x.errors, TokenError(token, fmt.Sprintf(format, args...)))
+}
+
+func (x *Checker) beginScope() {
+	x.scopes = append(x.scopes, make(map[string]binding))
+}
+
+func (x *Checker) endScope() {
+	x.scopes = x.scopes[:len(x.scopes)-1]
+}
+
+func (x *Checker) declare(name Token, variable binding) {
+	if len(x.scopes) == 0 {
+		x.globals[name.Lexeme] = variable
+	} else {
+		x.scopes[len(x.scopes)-1][name.Lexeme] = variable
+	}
+}
+
+// lookup returns the binding of the variable name refers to, which is Any if
+// it's a global declared later or never.
+func (x *Checker) lookup(name Token) binding {
+	for i := len(x.scopes) - 1; i >= 0; i-- {
+		if variable, ok := x.scopes[i][name.Lexeme]; ok {
+			return variable
+		}
+	}
+
+	if variable, ok := x.globals[name.Lexeme]; ok {
+		return variable
+	}
+
+	return binding{t: typeAny}
+}
+
+// annotation returns the type an annotation names: a built-in type or a
+// class.
+func (x *Checker) annotation(annotation *TypeAnnotation) staticType {
+	if annotation == nil {
+		return typeAny
+	}
+
+	if t, ok := annotationTypes[annotation.Name.Lexeme]; ok {
+		return t
+	}
+
+	if class, ok := x.lookup(annotation.Name).t.(classValue); ok {
+		return class.class
+	}
+
+	x.errorf(annotation.Name, "unknown type '%s'", annotation.Name.Lexeme)
+
+	return typeAny
+}
+
+func (x *Checker) signature(fn *FunctionStmt) *signature {
+	params := make([]staticType, len(fn.Params))
+	for i := range fn.Params {
+		params[i] = x.annotation(fn.ParamTypes[i])
+	}
+
+	return &signature{
+		name:      fn.Name.Lexeme,
+		params:    params,
+		result:    x.annotation(fn.ReturnType),
+		generator: fn.IsGenerator,
+	}
+}
+
+func (x *Checker) defineClass(stmt *ClassStmt, class *staticClass) {
+	if stmt.Superclass != nil {
+		if superclass, ok := x.lookup(stmt.Superclass.Name).t.(classValue); ok {
+			class.superclass = superclass.class
+		}
+	}
+
+	class.traits = len(stmt.Traits) > 0
+
+	class.fields = make(map[string]staticType, len(stmt.Fields))
+	for _, field := range stmt.Fields {
+		class.fields[field.Name.Lexeme] = x.annotation(field.Type)
+	}
+
+	class.methods = make(map[string]*signature, len(stmt.Methods))
+	for _, method := range stmt.Methods {
+		class.methods[method.Name.Lexeme] = x.signature(method)
+	}
+}
+
+// declarePattern declares the variables pattern binds: with the type it
+// tests for, if any, and otherwise Any.
+func (x *Checker) declarePattern(pattern Pattern) {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		t := staticType(typeAny)
+
+		if pattern.Type != nil {
+			if builtin, ok := annotationTypes[pattern.Type.Name.Lexeme]; ok {
+				t = builtin
+			} else if class, ok := x.lookup(pattern.Type.Name).t.(classValue); ok {
+				t = class.class
+			}
+		}
+
+		if pattern.Name.Lexeme != "_" {
+			x.declare(pattern.Name, binding{t: t})
+		}
+	case *ClassPattern:
+		for _, subpattern := range pattern.Subpatterns {
+			x.declarePattern(subpattern)
+		}
+	}
+}
+
+func (x *Checker) checkFunction(stmt *FunctionStmt, fn *signature) {
+	enclosing, enclosingGenerator := x.function, x.generator
+	x.function, x.generator = fn, stmt.IsGenerator
+
+	x.beginScope()
+
+	for i, param := range stmt.Params {
+		x.declare(param, binding{t: fn.params[i], declared: stmt.ParamTypes[i] != nil})
+	}
+
+	x.stmts(stmt.Body)
+	x.endScope()
+
+	// Falling off the end returns nil; initializers return 'this' instead.
+	initializer := x.class != nil && stmt.Name.Lexeme == "init"
+	if !stmt.IsGenerator && !initializer && !assignable(fn.result, typeNil) && completes(stmt.Body) {
+		x.errorf(stmt.Name, "'%s' must return %s but can end without returning", fn.name, fn.result)
+	}
+
+	x.function, x.generator = enclosing, enclosingGenerator
+}
+
+// completes reports whether running statements can reach their end, rather
+// than return or loop forever: Lox has no break, so a loop whose condition is
+// missing or true never ends.
+func completes(statements []Stmt) bool {
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ReturnStmt:
+			return false
+		case *BlockStmt:
+			if !completes(stmt.Statements) {
+				return false
+			}
+		case *IfStmt:
+			if stmt.ElseBranch != nil && !completes([]Stmt{stmt.ThenBranch}) && !completes([]Stmt{stmt.ElseBranch}) {
+				return false
+			}
+		case *WhileStmt:
+			if isTrue(stmt.Condition) {
+				return false
+			}
+		case *ForStmt:
+			if stmt.Condition == nil || isTrue(stmt.Condition) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isTrue(expr Expr) bool {
+	literal, ok := expr.(*Literal)
+
+	return ok && literal.Value == true
+}
+
+func (x *Checker) checkArguments(expr *Call, fn *signature, arguments []staticType) {
+	if fn.variadic {
+		return
+	}
+
+	if len(arguments) != len(fn.params) {
+		x.errorf(expr.Paren, "expected %d arguments but got %d", len(fn.params), len(arguments))
+		return
+	}
+
+	for i, argument := range arguments {
+		if !assignable(fn.params[i], argument) {
+			x.errorf(expr.Paren, "argument %d to '%s' must be %s, got %s", i+1, fn.name, fn.params[i], argument)
+		}
+	}
+}
+
+// endregion
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestCheckerAcceptsWorkingPrograms runs the checker over the scripts that
+// are expected to run without errors, none of which should get a type error.
+func TestCheckerAcceptsWorkingPrograms(t *testing.T) {
+	files, err := loxFiles([]string{"testdata", "../examples", "../challenges"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		test, source, err := loadGoldenTest(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.runtimeError != nil || len(test.compileErrors) > 0 || len(test.typeErrors) > 0 ||
+			strings.Contains(filepath.ToSlash(file), "_error/") {
+			continue
+		}
+
+		statements, err := parse(source)
+		if err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+
+		if _, err = NewResolver().Resolve(statements); err != nil {
+			t.Errorf("%s: %v", file, err)
+			continue
+		}
+
+		for _, err := range NewChecker().Check(statements) {
+			t.Errorf("%s: %s", file, strings.TrimSpace(err.Error()))
+		}
+	}
+}
+
+func TestCheckerInference(t *testing.T) {
+	tests := []struct {
+		source string
+		errors []string
+	}{
+		{`var n: Number = 1 + 2 * 3;`, nil},
+		{`var s: String = "a" + "b";`, nil},
+		{`var b: Bool = 1 < 2 and !nil;`, nil},
+		{`var s: String = 1 + 2;`, []string{"can't assign Number to 's' of type String"}},
+		{`const n = 1; var s: String = n;`, []string{"can't assign Number to 's' of type String"}},
+		{`var n = 1; var s: String = n;`, []string{"can't assign Number to 's' of type String"}},
+		{`var n = 1; n = "a"; var s: String = n;`, nil},
+		{`fun f() { var n = 1; fun g() { n++; } var s: String = n; }`, nil},
+		{`class P { n: Number; } var p = P(); p.n = "x";`, []string{"can't assign String to field 'n' of type Number"}},
+		{`var p = nil; while (p == nil) { print p; p = clock(); }`, nil},
+		{`fun h(): Number {}`, []string{"'h' must return Number but can end without returning"}},
+		{`fun h(a): Number { if (a) return 1; }`, []string{"'h' must return Number but can end without returning"}},
+		{`fun h(a): Number { if (a) return 1; else { return 2; } }`, nil},
+		{`fun h(): Number { while (true) {} }`, nil},
+		{`fun h(): String { print 1; } fun i(): Any {} fun j(): Nil {}`, []string{"'h' must return String but can end without returning"}},
+		{`class P { init(): P {} }`, nil},
+		{`fun f(): Number { return 1; } var s: String = f();`, []string{"can't assign Number to 's' of type String"}},
+		{`fun f(a: Number) {} f("a", 1);`, []string{"expected 1 arguments but got 2"}},
+		{`class A {} class B < A {} var a: A = B(); var b: B = A();`, []string{"can't assign A to 'b' of type B"}},
+		{`class A { x: String; } A().x = 1;`, []string{"can't assign Number to field 'x' of type String"}},
+		{`class A { __add(other) { return 1; } } var n = A() + 1;`, nil},
+		{`var f: Function = clock; var g: Function = nil; var n: Number = nil;`, []string{"can't assign Nil to 'n' of type Number"}},
+		{`print 1.len; true();`, []string{"only instances have properties", "can only call functions and classes"}},
+	}
+
+	for _, test := range tests {
+		statements, err := parse(test.source)
+		if err != nil {
+			t.Fatalf("%s: %v", test.source, err)
+		}
+
+		errs := NewChecker().Check(statements)
+
+		var messages []string
+		for _, err := range errs {
+			messages = append(messages, err.(*CompileError).Message)
+		}
+
+		if strings.Join(messages, "\n") != strings.Join(test.errors, "\n") {
+			t.Errorf("%s: expected errors %q, got %q", test.source, test.errors, messages)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// commands maps glox subcommands to their entry points. Each one receives the
+// arguments following the subcommand name and returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"ast":   astCommand,
+	"check": checkCommand,
+	"fmt":   fmtCommand,
+	"lsp":   lspCommand,
+	"debug": debugCommand,
+	"test":  testCommand,
+}
+
+func printUsage() {
+	fmt.Println("Usage: glox [script [args...]]")
+	fmt.Println("       glox ast [-format sexpr|tree|json|dot] script")
+	fmt.Println("       glox check path...")
+	fmt.Println("       glox fmt [-check | -write] path...")
+	fmt.Println("       glox lsp")
+	fmt.Println("       glox debug script [args...] | glox debug -dap")
+	fmt.Println("       glox test path...")
+}
+
+func astCommand(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	format := flags.String("format", astFormatSExpr, "output format: "+strings.Join(astFormats, ", "))
+
+	if err := flags.Parse(args); err != nil {
+		return 64
+	}
+
+	if flags.NArg() != 1 {
+		printUsage()
+		return 64
+	}
+
+	bytes, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return 66
+	}
+
+	statements, err := parse(string(bytes))
+	if err != nil {
+		fmt.Println(err)
+		return 65
+	}
+
+	output, err := (&astPrinter{}).Print(statements, *format)
+	if err != nil {
+		fmt.Println(err)
+		return 64
+	}
+
+	fmt.Print(output)
+
+	return 0
+}
+
+// checkCommand resolves and type checks each file without running it,
+// printing the errors found. Directories are searched recursively for .lox
+// files.
+func checkCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 64
+	}
+
+	files, err := loxFiles(args)
+	if err != nil {
+		fmt.Println(err)
+		return 66
+	}
+
+	status := 0
+
+	for _, file := range files {
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Println(err)
+			return 66
+		}
+
+		statements, err := parse(string(bytes))
+		if err == nil {
+			_, err = NewResolver().Resolve(statements)
+		}
+		if err != nil {
+			fmt.Printf("%s: %s\n", file, strings.TrimSpace(err.Error()))
+			status = 65
+			continue
+		}
+
+		for _, err = range NewChecker().Check(statements) {
+			fmt.Printf("%s: %s\n", file, strings.TrimSpace(err.Error()))
+			status = 65
+		}
+	}
+
+	return status
+}
+
+// fmtCommand prints the formatted source of each file, or with -check lists
+// the files that aren't formatted and fails, or with -write rewrites them in
+// place. Directories are searched recursively for .lox files.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "list files whose formatting differs and exit with status 1")
+	write := flags.Bool("write", false, "write the result to the source files instead of stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return 64
+	}
+
+	if flags.NArg() == 0 || *check && *write {
+		printUsage()
+		return 64
+	}
+
+	files, err := loxFiles(flags.Args())
+	if err != nil {
+		fmt.Println(err)
+		return 66
+	}
+
+	status := 0
+
+	for _, file := range files {
+		bytes, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Println(err)
+			return 66
+		}
+
+		formatted, err := Format(string(bytes))
+		if err != nil {
+			fmt.Printf("%s: %s\n", file, strings.TrimSpace(err.Error()))
+			status = 65
+			continue
+		}
+
+		switch {
+		case *check:
+			if formatted != string(bytes) {
+				fmt.Println(file)
+
+				if status == 0 {
+					status = 1
+				}
+			}
+		case *write:
+			if formatted != string(bytes) {
+				err = os.WriteFile(file, []byte(formatted), 0644)
+				if err != nil {
+					fmt.Println(err)
+					return 74
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+
+	return status
+}
+
+// loxFiles expands directories in paths into the .lox files they contain.
+func loxFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !entry.IsDir() && filepath.Ext(file) == ".lox" {
+				files = append(files, file)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// lspCommand runs a language server speaking LSP over stdin and stdout.
+func lspCommand(args []string) int {
+	if len(args) > 0 {
+		printUsage()
+		return 64
+	}
+
+	server := newLspServer(os.Stdin, os.Stdout)
+
+	if err := server.serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return server.exitCode
+}
+
+// debugCommand runs a script under the interactive debugger, or with -dap
+// serves the Debug Adapter Protocol on stdin and stdout, in which case the
+// script is named by the client's launch request.
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	dap := flags.Bool("dap", false, "speak the Debug Adapter Protocol over stdin/stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return 64
+	}
+
+	if *dap {
+		if flags.NArg() != 0 {
+			printUsage()
+			return 64
+		}
+
+		if err := newDapSession(os.Stdin, os.Stdout).serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		return 0
+	}
+
+	if flags.NArg() == 0 {
+		printUsage()
+		return 64
+	}
+
+	bytes, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		return 66
+	}
+
+	statements, spans, err := parseDebugProgram(string(bytes))
+	if err != nil {
+		fmt.Println(err)
+		return 65
+	}
+
+	interpreter := (&Interpreter{Policy: fullAccessPolicy(), Args: flags.Args()[1:]}).Init()
+
+	err = interpreter.Resolve(statements)
+	if err != nil {
+		fmt.Println(err)
+		return 65
+	}
+
+	console := &debugConsole{
+		input:  bufio.NewScanner(os.Stdin),
+		output: os.Stdout,
+		source: strings.Split(string(bytes), "\n"),
+	}
+	console.debugger = newDebugger(spans, console)
+	interpreter.debugger = console.debugger
+
+	fmt.Println("Debugging " + flags.Arg(0) + ", type 'help' for commands.")
+
+	err = interpreter.Interpret(statements)
+	if errors.Is(err, errDebuggerQuit) {
+		return 0
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if err != nil {
+		return 70
+	}
+
+	fmt.Println("Program finished.")
+
+	return 0
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// dapSession serves one debugging session over the Debug Adapter Protocol,
+// so editors can drive the same debugger as the console. The program runs
+// on its own goroutine once the client is done configuring breakpoints;
+// while it's stopped, requests inspecting the stack read the state the
+// interpreter goroutine left behind.
+type dapSession struct {
+	reader *bufio.Reader
+
+	writeMutex sync.Mutex
+	writer     io.Writer
+	seq        int
+
+	debugger   *debugger
+	program    string
+	args       []string
+	statements []Stmt
+	launched   bool
+	configured bool
+	started    bool
+	resume     chan stepMode
+
+	mutex   sync.Mutex // guards the fields below, shared with the interpreter goroutine
+	refs    map[int]any
+	closing bool
+	waiting bool // the program is stopped and nothing has resumed it yet
+}
+
+func newDapSession(reader io.Reader, writer io.Writer) *dapSession {
+	x := &dapSession{
+		reader: bufio.NewReader(reader),
+		writer: writer,
+		resume: make(chan stepMode),
+		refs:   make(map[int]any),
+	}
+
+	x.debugger = newDebugger(nil, x)
+
+	return x
+}
+
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+// serve handles requests until the client disconnects or closes the input.
+func (x *dapSession) serve() error {
+	for {
+		body, err := readMessage(x.reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request dapRequest
+		if err = json.Unmarshal(body, &request); err != nil {
+			return err
+		}
+
+		result, err := x.handle(request)
+
+		response := dapResponse{
+			Type:       "response",
+			RequestSeq: request.Seq,
+			Success:    err == nil,
+			Command:    request.Command,
+			Body:       result,
+		}
+		if err != nil {
+			response.Message = err.Error()
+		}
+
+		if err = x.send(&response.Seq, &response); err != nil {
+			return err
+		}
+
+		switch request.Command {
+		case "initialize":
+			if err = x.event("initialized", nil); err != nil {
+				return err
+			}
+		case "launch", "configurationDone":
+			x.start()
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+func (x *dapSession) handle(request dapRequest) (any, error) {
+	switch request.Command {
+	case "initialize":
+		return map[string]any{
+			"supportsConfigurationDoneRequest": true,
+			"supportsTerminateRequest":         true,
+		}, nil
+	case "launch":
+		var args struct {
+			Program     string   `json:"program"`
+			Args        []string `json:"args"`
+			StopOnEntry bool     `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return nil, x.launch(args.Program, args.Args, args.StopOnEntry)
+	case "setBreakpoints":
+		var args struct {
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		x.debugger.clearBreakpoints()
+
+		breakpoints := []map[string]any{}
+		for _, breakpoint := range args.Breakpoints {
+			x.debugger.setBreakpoint(breakpoint.Line, true)
+			breakpoints = append(breakpoints, map[string]any{"verified": true, "line": breakpoint.Line})
+		}
+
+		return map[string]any{"breakpoints": breakpoints}, nil
+	case "configurationDone":
+		x.configured = true
+		return nil, nil
+	case "threads":
+		return map[string]any{"threads": []map[string]any{{"id": 1, "name": "main"}}}, nil
+	case "stackTrace":
+		return map[string]any{"stackFrames": x.stackFrames(), "totalFrames": len(x.debugger.frames)}, nil
+	case "scopes":
+		var args struct {
+			FrameID int `json:"frameId"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"scopes": x.scopes(args.FrameID)}, nil
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"variables": x.variables(args.VariablesReference)}, nil
+	case "evaluate":
+		var args struct {
+			Expression string `json:"expression"`
+			FrameID    int    `json:"frameId"`
+		}
+		if err := json.Unmarshal(request.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		frame := x.frame(args.FrameID)
+		if frame == nil {
+			return nil, errors.New("not stopped")
+		}
+
+		value, ok := x.debugger.lookup(frame, args.Expression)
+		if !ok {
+			return nil, fmt.Errorf("undefined variable '%s'", args.Expression)
+		}
+
+		return map[string]any{"result": x.debugger.interpreter.inspect(value), "variablesReference": x.reference(value)}, nil
+	case "continue":
+		x.step(stepContinue)
+		return map[string]any{"allThreadsContinued": true}, nil
+	case "next":
+		x.step(stepOver)
+		return nil, nil
+	case "stepIn":
+		x.step(stepIn)
+		return nil, nil
+	case "stepOut":
+		x.step(stepOut)
+		return nil, nil
+	case "pause":
+		x.debugger.requestPause()
+		return nil, nil
+	case "disconnect", "terminate":
+		x.mutex.Lock()
+		x.closing = true
+		x.mutex.Unlock()
+
+		x.debugger.requestPause()
+		x.step(stepQuit)
+
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported request '%s'", request.Command)
+}
+
+func (x *dapSession) launch(program string, args []string, stopOnEntry bool) error {
+	source, err := os.ReadFile(program)
+	if err != nil {
+		return err
+	}
+
+	statements, spans, err := parseDebugProgram(string(source))
+	if err != nil {
+		return err
+	}
+
+	x.program = program
+	x.args = args
+	x.statements = statements
+	x.debugger.spans = spans
+	x.debugger.entry = stopOnEntry
+	x.launched = true
+
+	return nil
+}
+
+// start runs the program once it's launched and configured.
+func (x *dapSession) start() {
+	if !x.launched || !x.configured || x.started {
+		return
+	}
+
+	x.started = true
+
+	interpreter := (&Interpreter{
+		Stdout: dapOutput{x, "stdout"},
+		Stderr: dapOutput{x, "stderr"},
+		Policy: fullAccessPolicy(),
+		Args:   x.args,
+	}).Init()
+	interpreter.debugger = x.debugger
+
+	go func() {
+		exitCode := 0
+
+		err := interpreter.Resolve(x.statements)
+		if err == nil {
+			err = interpreter.Interpret(x.statements)
+		} else {
+			_, _ = fmt.Fprint(interpreter.Stderr, err)
+		}
+
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.Code
+		} else if err != nil && !errors.Is(err, errDebuggerQuit) {
+			exitCode = 70
+		}
+
+		_ = x.event("exited", map[string]any{"exitCode": exitCode})
+		_ = x.event("terminated", nil)
+	}()
+}
+
+// step resumes a stopped program. It does nothing while the program runs.
+func (x *dapSession) step(mode stepMode) {
+	x.mutex.Lock()
+	waiting := x.waiting
+	x.waiting = false
+	x.mutex.Unlock()
+
+	// The client may answer the stopped event before the program gets to
+	// wait for it, so the program is waited for rather than skipped.
+	if waiting {
+		x.resume <- mode
+	}
+}
+
+// stopped implements debugFrontend.
+func (x *dapSession) stopped(reason string) stepMode {
+	x.mutex.Lock()
+	closing := x.closing
+	x.refs = make(map[int]any)
+	x.waiting = !closing
+	x.mutex.Unlock()
+
+	if closing {
+		return stepQuit
+	}
+
+	_ = x.event("stopped", map[string]any{"reason": reason, "threadId": 1, "allThreadsStopped": true})
+
+	return <-x.resume
+}
+
+// frame maps a DAP frame id to a stack frame; ids count from 1 at the top.
+func (x *dapSession) frame(id int) *debugFrame {
+	frames := x.debugger.frames
+	if id < 1 || id > len(frames) {
+		return nil
+	}
+
+	return frames[len(frames)-id]
+}
+
+func (x *dapSession) stackFrames() []map[string]any {
+	frames := x.debugger.frames
+	result := make([]map[string]any, 0, len(frames))
+
+	for id := 1; id <= len(frames); id++ {
+		frame := x.frame(id)
+		result = append(result, map[string]any{
+			"id":     id,
+			"name":   frame.name(),
+			"line":   frame.line,
+			"column": 1,
+			"source": map[string]any{"path": x.program},
+		})
+	}
+
+	return result
+}
+
+func (x *dapSession) scopes(frameID int) []map[string]any {
+	frame := x.frame(frameID)
+	if frame == nil {
+		return []map[string]any{}
+	}
+
+	return []map[string]any{
+		{"name": "Locals", "variablesReference": x.addReference(x.debugger.locals(frame)), "expensive": false},
+		{"name": "Globals", "variablesReference": x.addReference(x.debugger.globals()), "expensive": false},
+	}
+}
+
+func (x *dapSession) variables(reference int) []map[string]any {
+	x.mutex.Lock()
+	target := x.refs[reference]
+	x.mutex.Unlock()
+
+	var variables []debugVariable
+
+	switch target := target.(type) {
+	case []debugVariable:
+		// Inner scopes shadow outer ones.
+		seen := make(map[string]bool)
+		for _, variable := range target {
+			if !seen[variable.name] {
+				seen[variable.name] = true
+				variables = append(variables, variable)
+			}
+		}
+	case *InstanceImpl:
+		environment := &Environment{values: target.snapshot()}
+		variables = x.debugger.variables(environment, 0)
+	}
+
+	result := make([]map[string]any, 0, len(variables))
+	for _, variable := range variables {
+		result = append(result, map[string]any{
+			"name":               variable.name,
+			"value":              x.debugger.interpreter.inspect(variable.value),
+			"variablesReference": x.reference(variable.value),
+		})
+	}
+
+	return result
+}
+
+// reference returns a handle clients can expand for values with fields, or 0.
+func (x *dapSession) reference(value any) int {
+	if instance, ok := value.(*InstanceImpl); ok && len(instance.snapshot()) > 0 {
+		return x.addReference(instance)
+	}
+
+	return 0
+}
+
+func (x *dapSession) addReference(target any) int {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	reference := len(x.refs) + 1
+	x.refs[reference] = target
+
+	return reference
+}
+
+func (x *dapSession) event(name string, body any) error {
+	event := dapEvent{Type: "event", Event: name, Body: body}
+
+	return x.send(&event.Seq, &event)
+}
+
+// send numbers and writes a message; seq points into message so the number
+// is assigned under the write lock.
+func (x *dapSession) send(seq *int, message any) error {
+	x.writeMutex.Lock()
+	defer x.writeMutex.Unlock()
+
+	x.seq++
+	*seq = x.seq
+
+	return writeMessage(x.writer, message)
+}
+
+// dapOutput forwards program output to the client as output events.
+type dapOutput struct {
+	session  *dapSession
+	category string
+}
+
+func (x dapOutput) Write(p []byte) (int, error) {
+	err := x.session.event("output", map[string]any{"category": x.category, "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
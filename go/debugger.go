@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type stepMode int
+
+const (
+	stepContinue stepMode = iota
+	stepIn
+	stepOver
+	stepOut
+	stepQuit
+)
+
+// Reasons execution can stop for, named as in the Debug Adapter Protocol.
+const (
+	stopEntry      = "entry"
+	stopBreakpoint = "breakpoint"
+	stopStep       = "step"
+	stopPause      = "pause"
+)
+
+var errDebuggerQuit = errors.New("debugger quit")
+
+// debugger is the debugHook behind `glox debug`. It keeps the Lox call stack,
+// decides when execution has to stop and hands control to a frontend, which
+// is either the interactive console or a Debug Adapter Protocol session.
+type debugger struct {
+	spans    map[Stmt]Span
+	frontend debugFrontend
+
+	// Guards the fields a frontend may change while the program runs.
+	mutex          sync.Mutex
+	breakpoints    map[int]bool
+	pauseRequested bool
+
+	interpreter *Interpreter
+	frames      []*debugFrame
+	mode        stepMode
+	depth       int // call depth the last step command was given at
+	entry       bool
+}
+
+// debugFrontend is called on the interpreter's goroutine whenever execution
+// stops, and returns how it should resume.
+type debugFrontend interface {
+	stopped(reason string) stepMode
+}
+
+type debugFrame struct {
+	function    *FunctionImpl // nil for the top-level script
+	environment *Environment
+	line        int
+}
+
+// parseDebugProgram parses source keeping the statement spans breakpoints and
+// stepping are based on.
+func parseDebugProgram(source string) ([]Stmt, map[Stmt]Span, error) {
+	tokens, err := NewScanner(source).ScanTokens()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parser := NewParser(tokens)
+
+	statements, err := parser.Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return statements, parser.Spans(), nil
+}
+
+func newDebugger(spans map[Stmt]Span, frontend debugFrontend) *debugger {
+	return &debugger{
+		spans:       spans,
+		frontend:    frontend,
+		breakpoints: make(map[int]bool),
+		frames:      []*debugFrame{{}},
+		entry:       true,
+	}
+}
+
+func (x *debugger) setBreakpoint(line int, enabled bool) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if enabled {
+		x.breakpoints[line] = true
+	} else {
+		delete(x.breakpoints, line)
+	}
+}
+
+func (x *debugger) clearBreakpoints() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.breakpoints = make(map[int]bool)
+}
+
+func (x *debugger) breakpointLines() []int {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	lines := make([]int, 0, len(x.breakpoints))
+	for line := range x.breakpoints {
+		lines = append(lines, line)
+	}
+
+	sort.Ints(lines)
+
+	return lines
+}
+
+// requestPause makes the program stop before its next statement.
+func (x *debugger) requestPause() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.pauseRequested = true
+}
+
+// region debugHook
+func (x *debugger) beforeStatement(interpreter *Interpreter, stmt Stmt) error {
+	span, ok := x.spans[stmt]
+	if !ok {
+		return nil
+	}
+
+	x.interpreter = interpreter
+
+	frame := x.frames[len(x.frames)-1]
+	changed := span.Start != frame.line
+	frame.line = span.Start
+	frame.environment = interpreter.environment
+
+	depth := len(x.frames)
+
+	x.mutex.Lock()
+	breakpoint := x.breakpoints[span.Start]
+	pause := x.pauseRequested
+	x.pauseRequested = false
+	x.mutex.Unlock()
+
+	reason := ""
+
+	switch {
+	case x.entry:
+		reason = stopEntry
+		x.entry = false
+	case pause:
+		reason = stopPause
+	case x.mode == stepIn && changed,
+		x.mode == stepOver && (depth < x.depth || depth == x.depth && changed),
+		x.mode == stepOut && depth < x.depth:
+		reason = stopStep
+	case breakpoint && changed:
+		reason = stopBreakpoint
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	x.mode = x.frontend.stopped(reason)
+	x.depth = depth
+
+	if x.mode == stepQuit {
+		return errDebuggerQuit
+	}
+
+	return nil
+}
+
+func (x *debugger) enterFunction(function *FunctionImpl, environment *Environment) {
+	x.frames = append(x.frames, &debugFrame{function: function, environment: environment})
+}
+
+func (x *debugger) exitFunction(_ *FunctionImpl) {
+	x.frames = x.frames[:len(x.frames)-1]
+}
+
+// endregion
+
+// region inspection
+func (x *debugFrame) name() string {
+	if x.function == nil {
+		return "<script>"
+	}
+
+	return x.function.declaration.Name.Lexeme
+}
+
+// debugVariable is one name visible from a frame, innermost scope first.
+type debugVariable struct {
+	name  string
+	value any
+	scope int // 0 for the innermost scope
+}
+
+// locals walks the environment chain of frame up to, but not including, the
+// globals.
+func (x *debugger) locals(frame *debugFrame) []debugVariable {
+	var variables []debugVariable
+
+	scope := 0
+	for environment := frame.environment; environment != nil && environment != x.interpreter.globals; environment = environment.enclosing {
+		variables = append(variables, x.variables(environment, scope)...)
+		scope++
+	}
+
+	return variables
+}
+
+func (x *debugger) globals() []debugVariable {
+	return x.variables(x.interpreter.globals, 0)
+}
+
+func (x *debugger) variables(environment *Environment, scope int) []debugVariable {
+	values := environment.snapshot()
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	variables := make([]debugVariable, 0, len(names))
+	for _, name := range names {
+		variables = append(variables, debugVariable{name, values[name], scope})
+	}
+
+	return variables
+}
+
+// lookup finds name in the scopes visible from frame, then in the globals.
+func (x *debugger) lookup(frame *debugFrame, name string) (any, bool) {
+	for environment := frame.environment; environment != nil; environment = environment.enclosing {
+		if value, ok := environment.GetAt(0, name); ok {
+			return value, true
+		}
+	}
+
+	return x.interpreter.globals.GetAt(0, name)
+}
+
+// endregion
+
+// region console frontend
+const debugConsoleHelp = `Commands:
+  break LINE (b)     set a breakpoint
+  delete LINE (d)    remove a breakpoint
+  breakpoints        list breakpoints
+  continue (c)       run until the next breakpoint
+  step (s)           step into the next statement
+  next (n)           step over function calls
+  out (o)            run until the current function returns
+  backtrace (bt)     show the call stack
+  locals (l)         show local variables of the current frame
+  globals (g)        show global variables
+  print NAME (p)     show a variable
+  list               show the source around the current line
+  quit (q)           stop the program
+`
+
+// debugConsole drives a debugger interactively from a terminal.
+type debugConsole struct {
+	debugger *debugger
+	input    *bufio.Scanner
+	output   io.Writer
+	source   []string
+}
+
+func (x *debugConsole) stopped(reason string) stepMode {
+	frame := x.debugger.frames[len(x.debugger.frames)-1]
+
+	_, _ = fmt.Fprintf(x.output, "Stopped (%s) in %s at line %d\n", reason, frame.name(), frame.line)
+	x.printLine(frame.line)
+
+	for {
+		_, _ = fmt.Fprint(x.output, "(glox) ")
+
+		if !x.input.Scan() {
+			return stepQuit
+		}
+
+		fields := strings.Fields(x.input.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "c", "continue":
+			return stepContinue
+		case "s", "step":
+			return stepIn
+		case "n", "next":
+			return stepOver
+		case "o", "out", "finish":
+			return stepOut
+		case "q", "quit":
+			return stepQuit
+		case "b", "break", "d", "delete":
+			line, err := x.lineArgument(args)
+			if err != nil {
+				_, _ = fmt.Fprintln(x.output, err)
+				continue
+			}
+
+			enabled := command == "b" || command == "break"
+			x.debugger.setBreakpoint(line, enabled)
+
+			if enabled {
+				_, _ = fmt.Fprintf(x.output, "Breakpoint set at line %d\n", line)
+			} else {
+				_, _ = fmt.Fprintf(x.output, "Breakpoint at line %d deleted\n", line)
+			}
+		case "breakpoints":
+			lines := x.debugger.breakpointLines()
+			if len(lines) == 0 {
+				_, _ = fmt.Fprintln(x.output, "No breakpoints.")
+			}
+
+			for _, line := range lines {
+				_, _ = fmt.Fprintf(x.output, "Breakpoint at line %d\n", line)
+			}
+		case "bt", "backtrace":
+			frames := x.debugger.frames
+			for i := len(frames) - 1; i >= 0; i-- {
+				_, _ = fmt.Fprintf(x.output, "#%d %s at line %d\n", len(frames)-1-i, frames[i].name(), frames[i].line)
+			}
+		case "l", "locals":
+			x.printVariables(x.debugger.locals(frame))
+		case "g", "globals":
+			x.printVariables(x.debugger.globals())
+		case "p", "print":
+			if len(args) != 1 {
+				_, _ = fmt.Fprintln(x.output, "usage: print NAME")
+				continue
+			}
+
+			if value, ok := x.debugger.lookup(frame, args[0]); ok {
+				_, _ = fmt.Fprintf(x.output, "%s = %s\n", args[0], x.debugger.interpreter.inspect(value))
+			} else {
+				_, _ = fmt.Fprintf(x.output, "undefined variable '%s'\n", args[0])
+			}
+		case "list":
+			for line := frame.line - 3; line <= frame.line+3; line++ {
+				x.printLine(line)
+			}
+		case "h", "help":
+			_, _ = fmt.Fprint(x.output, debugConsoleHelp)
+		default:
+			_, _ = fmt.Fprintf(x.output, "unknown command '%s', type 'help' for a list\n", command)
+		}
+	}
+}
+
+func (x *debugConsole) lineArgument(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("expected a line number")
+	}
+
+	line, err := strconv.Atoi(args[0])
+	if err != nil || line < 1 {
+		return 0, fmt.Errorf("invalid line number '%s'", args[0])
+	}
+
+	return line, nil
+}
+
+func (x *debugConsole) printLine(line int) {
+	if line < 1 || line > len(x.source) {
+		return
+	}
+
+	marker := "  "
+	if line == x.debugger.frames[len(x.debugger.frames)-1].line {
+		marker = "=>"
+	}
+
+	_, _ = fmt.Fprintf(x.output, "%s %4d  %s\n", marker, line, x.source[line-1])
+}
+
+func (x *debugConsole) printVariables(variables []debugVariable) {
+	if len(variables) == 0 {
+		_, _ = fmt.Fprintln(x.output, "(none)")
+		return
+	}
+
+	for _, variable := range variables {
+		indent := strings.Repeat("  ", variable.scope)
+		_, _ = fmt.Fprintf(x.output, "%s%s = %s\n", indent, variable.name, x.debugger.interpreter.inspect(variable.value))
+	}
+}
+
+// endregion
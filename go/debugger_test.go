@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const debuggedSource = `fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+var x = add(1, 2);
+print x;
+`
+
+func TestDebugConsoleSession(t *testing.T) {
+	statements, spans, err := parseDebugProgram(debuggedSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output strings.Builder
+
+	interpreter := (&Interpreter{Stdout: &output}).Init()
+	if err = interpreter.Resolve(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	console := &debugConsole{
+		input:  bufio.NewScanner(strings.NewReader("b 2\nc\nl\ns\nl\no\np x\nc\n")),
+		output: &output,
+		source: strings.Split(debuggedSource, "\n"),
+	}
+	console.debugger = newDebugger(spans, console)
+	interpreter.debugger = console.debugger
+
+	if err = interpreter.Interpret(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `Stopped (entry) in <script> at line 1
+=>    1  fun add(a, b) {
+(glox) Breakpoint set at line 2
+(glox) Stopped (breakpoint) in add at line 2
+=>    2    var sum = a + b;
+(glox) a = 1
+b = 2
+(glox) Stopped (step) in add at line 3
+=>    3    return sum;
+(glox) a = 1
+b = 2
+sum = 3
+(glox) Stopped (step) in <script> at line 7
+=>    7  print x;
+(glox) x = 3
+(glox) 3
+`
+	if output.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output.String())
+	}
+}
+
+// dapClient plays the editor's side of a DAP session, keeping the events
+// the adapter sends between responses.
+type dapClient struct {
+	t      *testing.T
+	input  io.Writer
+	output *bufio.Reader
+	seq    int
+	events []map[string]json.RawMessage
+}
+
+func (x *dapClient) read() map[string]json.RawMessage {
+	x.t.Helper()
+
+	body, err := readMessage(x.output)
+	if err != nil {
+		x.t.Fatal(err)
+	}
+
+	var message map[string]json.RawMessage
+	decode(x.t, body, &message)
+
+	return message
+}
+
+// request sends a request and returns the body of its response.
+func (x *dapClient) request(command string, arguments any) json.RawMessage {
+	x.t.Helper()
+
+	x.seq++
+	err := writeMessage(x.input, map[string]any{"seq": x.seq, "type": "request", "command": command, "arguments": arguments})
+	if err != nil {
+		x.t.Fatal(err)
+	}
+
+	for {
+		message := x.read()
+
+		var kind string
+		decode(x.t, message["type"], &kind)
+
+		if kind == "event" {
+			x.events = append(x.events, message)
+			continue
+		}
+
+		var response dapResponse
+		decode(x.t, message["request_seq"], &response.RequestSeq)
+		decode(x.t, message["success"], &response.Success)
+
+		if response.RequestSeq != x.seq {
+			x.t.Fatalf("expected the response to %d, got %v", x.seq, message)
+		}
+		if !response.Success {
+			x.t.Fatalf("%s failed: %s", command, message["message"])
+		}
+
+		return message["body"]
+	}
+}
+
+// event returns the body of the next event with the given name, skipping
+// others.
+func (x *dapClient) event(name string) json.RawMessage {
+	x.t.Helper()
+
+	for {
+		var message map[string]json.RawMessage
+		if len(x.events) > 0 {
+			message, x.events = x.events[0], x.events[1:]
+		} else {
+			message = x.read()
+		}
+
+		var event string
+		decode(x.t, message["event"], &event)
+
+		if event == name {
+			return message["body"]
+		}
+	}
+}
+
+// stopped waits for the program to stop and returns the reason and the
+// name and line of the top frame.
+func (x *dapClient) stopped() (string, string, int) {
+	x.t.Helper()
+
+	var stop struct {
+		Reason string `json:"reason"`
+	}
+	decode(x.t, x.event("stopped"), &stop)
+
+	var trace struct {
+		StackFrames []struct {
+			Name string `json:"name"`
+			Line int    `json:"line"`
+		} `json:"stackFrames"`
+	}
+	decode(x.t, x.request("stackTrace", map[string]any{"threadId": 1}), &trace)
+
+	if len(trace.StackFrames) == 0 {
+		x.t.Fatal("stopped without a stack")
+	}
+
+	return stop.Reason, trace.StackFrames[0].Name, trace.StackFrames[0].Line
+}
+
+func TestDapSession(t *testing.T) {
+	program := filepath.Join(t.TempDir(), "add.lox")
+	if err := os.WriteFile(program, []byte(debuggedSource), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputReader, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- newDapSession(inputReader, outputWriter).serve()
+	}()
+
+	// A session that hangs fails the test rather than the whole run.
+	timer := time.AfterFunc(5*time.Second, func() {
+		_ = outputReader.CloseWithError(errors.New("session timed out"))
+	})
+	defer timer.Stop()
+
+	client := &dapClient{t: t, input: inputWriter, output: bufio.NewReader(outputReader)}
+
+	client.request("initialize", map[string]any{"adapterID": "glox"})
+	client.event("initialized")
+
+	client.request("launch", map[string]any{"program": program})
+	client.request("setBreakpoints", map[string]any{
+		"source":      map[string]any{"path": program},
+		"breakpoints": []map[string]any{{"line": 2}},
+	})
+	client.request("configurationDone", nil)
+
+	if reason, name, line := client.stopped(); reason != "breakpoint" || name != "add" || line != 2 {
+		t.Fatalf("expected a breakpoint in add at line 2, got %s in %s at line %d", reason, name, line)
+	}
+
+	var scopes struct {
+		Scopes []struct {
+			Name               string `json:"name"`
+			VariablesReference int    `json:"variablesReference"`
+		} `json:"scopes"`
+	}
+	decode(t, client.request("scopes", map[string]any{"frameId": 1}), &scopes)
+
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" {
+		t.Fatalf("expected locals and globals, got %+v", scopes.Scopes)
+	}
+
+	var variables struct {
+		Variables []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+	decode(t, client.request("variables", map[string]any{"variablesReference": scopes.Scopes[0].VariablesReference}), &variables)
+
+	locals := make(map[string]string)
+	for _, variable := range variables.Variables {
+		locals[variable.Name] = variable.Value
+	}
+
+	if !reflect.DeepEqual(locals, map[string]string{"a": "1", "b": "2"}) {
+		t.Errorf("expected a = 1 and b = 2, got %v", locals)
+	}
+
+	client.request("next", map[string]any{"threadId": 1})
+	if reason, name, line := client.stopped(); reason != "step" || name != "add" || line != 3 {
+		t.Fatalf("expected a step to line 3 of add, got %s in %s at line %d", reason, name, line)
+	}
+
+	client.request("stepOut", map[string]any{"threadId": 1})
+	if reason, name, line := client.stopped(); reason != "step" || name != "<script>" || line != 7 {
+		t.Fatalf("expected a step out to line 7, got %s in %s at line %d", reason, name, line)
+	}
+
+	client.request("continue", map[string]any{"threadId": 1})
+
+	var output struct {
+		Category string `json:"category"`
+		Output   string `json:"output"`
+	}
+	decode(t, client.event("output"), &output)
+
+	if output.Category != "stdout" || output.Output != "3\n" {
+		t.Errorf("expected the program to print 3, got %+v", output)
+	}
+
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	decode(t, client.event("exited"), &exited)
+
+	if exited.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", exited.ExitCode)
+	}
+
+	client.event("terminated")
+	client.request("disconnect", nil)
+
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,12 +1,22 @@
 package main
 
+import "sync"
+
+// Environment is safe for concurrent use: closures and globals are shared
+// between the tasks of a program.
 type Environment struct {
+	mutex     sync.RWMutex
 	values    map[string]any
+	constants map[string]bool // names declared with 'const', checked by Assign
 	enclosing *Environment
 }
 
 func (x *Environment) Get(name Token) (any, error) {
-	if val, ok := x.values[name.Lexeme]; ok {
+	x.mutex.RLock()
+	val, ok := x.values[name.Lexeme]
+	x.mutex.RUnlock()
+
+	if ok {
 		return val, nil
 	}
 
@@ -18,26 +28,67 @@ func (x *Environment) Get(name Token) (any, error) {
 }
 
 func (x *Environment) GetAt(distance int, name string) (any, bool) {
-	value, ok := x.Ancestor(distance).values[name]
+	environment := x.Ancestor(distance)
+
+	environment.mutex.RLock()
+	defer environment.mutex.RUnlock()
+
+	value, ok := environment.values[name]
 
 	return value, ok
 }
 
 func (x *Environment) Define(name string, value any) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
 	x.ensureValuesInitialized()
 
 	x.values[name] = value
+	delete(x.constants, name)
 }
 
-func (x *Environment) Assign(name Token, value any) error {
+// DefineConstant defines a variable that Assign refuses to change. Local
+// constants are enforced by the Resolver, which leaves only globals to it.
+func (x *Environment) DefineConstant(name string, value any) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
 	x.ensureValuesInitialized()
 
+	if x.constants == nil {
+		x.constants = map[string]bool{}
+	}
+
+	x.values[name] = value
+	x.constants[name] = true
+}
+
+func (x *Environment) isConstant(name string) bool {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	return x.constants[name]
+}
+
+func (x *Environment) Assign(name Token, value any) error {
+	x.mutex.Lock()
+
 	if _, ok := x.values[name.Lexeme]; ok {
+		if x.constants[name.Lexeme] {
+			x.mutex.Unlock()
+
+			return RuntimeError{"can't assign to constant '" + name.Lexeme + "'", name}
+		}
+
 		x.values[name.Lexeme] = value
+		x.mutex.Unlock()
 
 		return nil
 	}
 
+	x.mutex.Unlock()
+
 	if x.enclosing != nil {
 		return x.enclosing.Assign(name, value)
 	}
@@ -46,7 +97,14 @@ func (x *Environment) Assign(name Token, value any) error {
 }
 
 func (x *Environment) AssignAt(distance int, name Token, value any) {
-	x.Ancestor(distance).values[name.Lexeme] = value
+	environment := x.Ancestor(distance)
+
+	environment.mutex.Lock()
+	defer environment.mutex.Unlock()
+
+	environment.ensureValuesInitialized()
+
+	environment.values[name.Lexeme] = value
 }
 
 func (x *Environment) Ancestor(distance int) *Environment {
@@ -58,6 +116,19 @@ func (x *Environment) Ancestor(distance int) *Environment {
 	return environment
 }
 
+// snapshot returns a copy of the variables defined in x itself.
+func (x *Environment) snapshot() map[string]any {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	values := make(map[string]any, len(x.values))
+	for name, value := range x.values {
+		values[name] = value
+	}
+
+	return values
+}
+
 func (x *Environment) ensureValuesInitialized() {
 	if x.values == nil {
 		x.values = map[string]any{}
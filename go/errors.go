@@ -5,18 +5,41 @@ import (
 	"strings"
 )
 
+// CompileError is a static error found while scanning, parsing or resolving
+// a script.
+type CompileError struct {
+	Line    int
+	Token   *Token // offending token, nil when only the line is known
+	Where   string
+	Message string
+}
+
+func (x *CompileError) Error() string {
+	return fmt.Sprintf("[line %d] Error%s: %s\n", x.Line, x.Where, x.Message)
+}
+
 func LineError(line int, message string) error {
 	return ReportError(line, message, "")
 }
 
 func TokenError(token Token, message string) error {
+	where := " at '" + token.Lexeme + "'"
 	if token.Type == EOF {
-		return ReportError(token.Line, message, " at end")
+		where = " at end"
 	}
 
-	return ReportError(token.Line, message, " at '"+token.Lexeme+"'")
+	return &CompileError{
+		Line:    token.Line,
+		Token:   &token,
+		Where:   where,
+		Message: message,
+	}
 }
 
 func ReportError(line int, message string, where ...string) error {
-	return fmt.Errorf("[line %d] Error%s: %s\n", line, strings.Join(where, ", "), message)
+	return &CompileError{
+		Line:    line,
+		Where:   strings.Join(where, ", "),
+		Message: message,
+	}
 }
@@ -14,6 +14,13 @@ type ExprVisitor interface {
 	VisitSetExpr(expr *Set) (any, error)
 	VisitThisExpr(expr *ThisExpr) (any, error)
 	VisitSuperExpr(expr *SuperExpr) (any, error)
+	VisitSpawnExpr(expr *SpawnExpr) (any, error)
+	VisitIndexExpr(expr *IndexExpr) (any, error)
+	VisitSetIndexExpr(expr *SetIndexExpr) (any, error)
+	VisitMatchExpr(expr *MatchExpr) (any, error)
+	VisitConditionalExpr(expr *ConditionalExpr) (any, error)
+	VisitUpdateExpr(expr *UpdateExpr) (any, error)
+	VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error)
 }
 
 // Expressions
@@ -64,9 +71,12 @@ func (x *Variable) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitVariableExpr(x)
 }
 
+// Assign stores Value in a variable. Operator is the '=' or, for compound
+// assignments like 'x += 1', the operator combining Value with the variable.
 type Assign struct {
-	Name  Token
-	Value Expr
+	Name     Token
+	Operator Token
+	Value    Expr
 }
 
 func (x *Assign) Accept(visitor ExprVisitor) (any, error) {
@@ -93,9 +103,12 @@ func (x *Call) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitCallExpr(x)
 }
 
+// Get reads a property. With Optional, written 'object?.name', a nil object
+// ends the enclosing OptionalChainExpr, which evaluates to nil.
 type Get struct {
-	Object Expr
-	Name   Token
+	Object   Expr
+	Name     Token
+	Optional bool
 }
 
 func (x *Get) Accept(visitor ExprVisitor) (any, error) {
@@ -103,9 +116,10 @@ func (x *Get) Accept(visitor ExprVisitor) (any, error) {
 }
 
 type Set struct {
-	Object Expr
-	Name   Token
-	Value  Expr
+	Object   Expr
+	Name     Token
+	Operator Token
+	Value    Expr
 }
 
 func (x *Set) Accept(visitor ExprVisitor) (any, error) {
@@ -128,3 +142,119 @@ type SuperExpr struct {
 func (x *SuperExpr) Accept(visitor ExprVisitor) (any, error) {
 	return visitor.VisitSuperExpr(x)
 }
+
+type SpawnExpr struct {
+	Keyword Token
+	Call    *Call
+}
+
+func (x *SpawnExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSpawnExpr(x)
+}
+
+type IndexExpr struct {
+	Object  Expr
+	Bracket Token
+	Index   Expr
+}
+
+func (x *IndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitIndexExpr(x)
+}
+
+type SetIndexExpr struct {
+	Object   Expr
+	Bracket  Token
+	Index    Expr
+	Operator Token
+	Value    Expr
+}
+
+func (x *SetIndexExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitSetIndexExpr(x)
+}
+
+type ConditionalExpr struct {
+	Condition Expr
+	Question  Token
+	Then      Expr
+	Else      Expr
+}
+
+func (x *ConditionalExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitConditionalExpr(x)
+}
+
+// UpdateExpr increments or decrements Target, a variable, property or
+// index. A prefix update evaluates to the new value, a postfix one to the
+// old.
+type UpdateExpr struct {
+	Target   Expr
+	Operator Token
+	Prefix   bool
+}
+
+func (x *UpdateExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitUpdateExpr(x)
+}
+
+// OptionalChainExpr wraps a chain of calls, property accesses and indexes
+// containing a '?.', so that the chain stops at the first '?.' on nil.
+type OptionalChainExpr struct {
+	Chain Expr
+}
+
+func (x *OptionalChainExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitOptionalChainExpr(x)
+}
+
+type MatchExpr struct {
+	Keyword Token
+	Value   Expr
+	Arms    []*MatchArm
+}
+
+func (x *MatchExpr) Accept(visitor ExprVisitor) (any, error) {
+	return visitor.VisitMatchExpr(x)
+}
+
+// MatchArm is a 'case' of a match expression: it's taken when the value
+// matches one of the patterns and the guard, if any, is truthy.
+type MatchArm struct {
+	Keyword  Token
+	Patterns []Pattern
+	Guard    Expr
+	Body     Expr
+}
+
+// Pattern is a LiteralPattern, BindingPattern or ClassPattern.
+type Pattern interface {
+	pattern()
+}
+
+// LiteralPattern matches values equal to a number, string, boolean or nil.
+type LiteralPattern struct {
+	Token Token
+	Value any
+}
+
+// BindingPattern matches any value, or with a type only values of that type,
+// and binds it to a variable unless the name is '_'. The type is a built-in
+// type name or a variable holding a class or trait.
+type BindingPattern struct {
+	Name Token
+	Type *Variable
+}
+
+// ClassPattern matches instances of a class, and their fields against the
+// subpatterns: as many as the initializer has parameters, each matched with
+// the field named like the parameter.
+type ClassPattern struct {
+	Class       *Variable
+	Paren       Token
+	Subpatterns []Pattern
+}
+
+func (*LiteralPattern) pattern() {}
+func (*BindingPattern) pattern() {}
+func (*ClassPattern) pattern()   {}
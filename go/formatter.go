@@ -0,0 +1,517 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Format returns source pretty-printed in the canonical glox style: two-space
+// indentation, K&R braces, one statement per line and single spaces around
+// binary operators. Comments are taken from the trivia token stream and put
+// back next to the statement they were written before or after; runs of blank
+// lines between statements collapse into one.
+func Format(source string) (string, error) {
+	tokens, err := NewTriviaScanner(source).ScanTokens()
+	if err != nil {
+		return "", err
+	}
+
+	var code, comments []Token
+	for _, token := range tokens {
+		if token.Type == Comment {
+			comments = append(comments, token)
+		} else {
+			code = append(code, token)
+		}
+	}
+
+	parser := NewParser(code)
+
+	statements, err := parser.Parse()
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{spans: parser.Spans(), comments: comments}
+	f.statements(statements, math.MaxInt, f.stmt)
+
+	return f.builder.String(), nil
+}
+
+type formatter struct {
+	builder  strings.Builder
+	indent   int
+	spans    map[Stmt]Span
+	comments []Token // not yet emitted, in source order
+	lastLine int     // source line the last emitted item ended on, 0 at the start of a block
+}
+
+// statements writes one line per statement (plus any comments around them)
+// at the current indentation. Comments starting before the closing line are
+// flushed at the end, so they stay inside the enclosing block.
+func (x *formatter) statements(statements []Stmt, closing int, write func(Stmt)) {
+	x.lastLine = 0
+
+	for _, stmt := range statements {
+		span := x.spans[stmt]
+
+		x.commentsBefore(span.Start)
+		x.separate(span.Start)
+
+		x.writeIndent()
+		write(stmt)
+
+		var within []Token
+		inner := false
+		for len(x.comments) > 0 && x.comments[0].Line <= span.End {
+			within = append(within, x.comments[0])
+			inner = inner || x.comments[0].Line < span.End
+			x.comments = x.comments[1:]
+		}
+
+		// Comments from inside a single-line construct, such as between call
+		// arguments spread over several lines, can't stay where they were, so
+		// they follow it in order, along with any on its last line.
+		if !inner {
+			for _, comment := range within {
+				x.builder.WriteString(" ")
+				x.builder.WriteString(x.commentText(comment))
+			}
+		}
+
+		x.builder.WriteString("\n")
+
+		if inner {
+			for _, comment := range within {
+				x.writeComment(comment)
+			}
+		}
+
+		x.lastLine = span.End
+	}
+
+	x.commentsBefore(closing)
+}
+
+func (x *formatter) commentsBefore(line int) {
+	for len(x.comments) > 0 && x.comments[0].Line < line {
+		x.separate(x.comments[0].Line)
+		x.writeComment(x.comments[0])
+
+		x.comments = x.comments[1:]
+	}
+}
+
+func (x *formatter) writeComment(comment Token) {
+	text := x.commentText(comment)
+
+	x.writeIndent()
+	x.builder.WriteString(text)
+	x.builder.WriteString("\n")
+
+	x.lastLine = comment.Line + strings.Count(text, "\n")
+}
+
+// commentText drops trailing whitespace, which an unterminated block comment
+// running to the end of the file would otherwise carry along.
+func (x *formatter) commentText(comment Token) string {
+	return strings.TrimRight(comment.Lexeme, " \t\r\n")
+}
+
+// separate keeps a single blank line where the source had one or more.
+func (x *formatter) separate(line int) {
+	if x.lastLine > 0 && line > x.lastLine+1 {
+		x.builder.WriteString("\n")
+	}
+}
+
+func (x *formatter) writeIndent() {
+	x.builder.WriteString(strings.Repeat("  ", x.indent))
+}
+
+func (x *formatter) block(statements []Stmt, closing int, write func(Stmt)) {
+	x.builder.WriteString("{")
+
+	if len(statements) == 0 && (len(x.comments) == 0 || x.comments[0].Line >= closing) {
+		x.builder.WriteString("}")
+		return
+	}
+
+	x.builder.WriteString("\n")
+
+	x.indent++
+	x.statements(statements, closing, write)
+	x.indent--
+
+	x.writeIndent()
+	x.builder.WriteString("}")
+}
+
+// body writes the body of an if, while or for statement. Blocks open on the
+// same line; any other statement follows the header after a single space.
+func (x *formatter) body(stmt Stmt) {
+	x.builder.WriteString(" ")
+	x.stmt(stmt)
+}
+
+func (x *formatter) stmt(stmt Stmt) {
+	_ = stmt.Accept(x)
+}
+
+func (x *formatter) member(stmt Stmt) {
+	if field, ok := stmt.(*fieldMember); ok {
+		x.builder.WriteString(field.Name.Lexeme + annotation(field.Type) + ";")
+		return
+	}
+
+	x.function(stmt.(*FunctionStmt))
+}
+
+func (x *formatter) function(stmt *FunctionStmt) {
+	params := make([]string, 0, len(stmt.Params))
+	for i, param := range stmt.Params {
+		params = append(params, param.Lexeme+annotation(stmt.ParamTypes[i]))
+	}
+
+	x.builder.WriteString(stmt.Name.Lexeme + "(" + strings.Join(params, ", ") + ")" + annotation(stmt.ReturnType) + " ")
+	x.block(stmt.Body, x.spans[stmt].End, x.stmt)
+}
+
+// annotation returns the ': Type' written after a name, if it has one.
+func annotation(annotation *TypeAnnotation) string {
+	if annotation == nil {
+		return ""
+	}
+
+	return ": " + annotation.Name.Lexeme
+}
+
+// fieldMember lets the field declarations of a class body be laid out among
+// its methods, which are statements.
+type fieldMember struct {
+	*FieldDecl
+}
+
+func (x *fieldMember) Accept(StmtVisitor) error {
+	return nil
+}
+
+func (x *formatter) expr(expr Expr) string {
+	value, _ := expr.Accept(x)
+
+	return value.(string)
+}
+
+// region Statement visitor methods
+func (x *formatter) VisitExpressionStmt(stmt *ExpressionStmt) error {
+	x.builder.WriteString(x.expr(stmt.Expression) + ";")
+
+	return nil
+}
+
+func (x *formatter) VisitPrintStmt(stmt *PrintStmt) error {
+	x.builder.WriteString("print " + x.expr(stmt.Expression) + ";")
+
+	return nil
+}
+
+func (x *formatter) VisitVarStmt(stmt *VarStmt) error {
+	if stmt.Constant {
+		x.builder.WriteString("const " + stmt.Name.Lexeme)
+	} else {
+		x.builder.WriteString("var " + stmt.Name.Lexeme)
+	}
+
+	x.builder.WriteString(annotation(stmt.Type))
+
+	if stmt.Initializer != nil {
+		x.builder.WriteString(" = " + x.expr(stmt.Initializer))
+	}
+
+	x.builder.WriteString(";")
+
+	return nil
+}
+
+func (x *formatter) VisitBlockStmt(stmt *BlockStmt) error {
+	x.block(stmt.Statements, x.spans[stmt].End, x.stmt)
+
+	return nil
+}
+
+func (x *formatter) VisitIfStmt(stmt *IfStmt) error {
+	x.builder.WriteString("if (" + x.expr(stmt.Condition) + ")")
+	x.body(stmt.ThenBranch)
+
+	if stmt.ElseBranch != nil {
+		x.builder.WriteString(" else")
+		x.body(stmt.ElseBranch)
+	}
+
+	return nil
+}
+
+func (x *formatter) VisitWhileStmt(stmt *WhileStmt) error {
+	x.builder.WriteString("while (" + x.expr(stmt.Condition) + ")")
+	x.body(stmt.Body)
+
+	return nil
+}
+
+func (x *formatter) VisitForStmt(stmt *ForStmt) error {
+	x.builder.WriteString("for (")
+
+	if stmt.Initializer != nil {
+		x.stmt(stmt.Initializer)
+	} else {
+		x.builder.WriteString(";")
+	}
+
+	if stmt.Condition != nil {
+		x.builder.WriteString(" " + x.expr(stmt.Condition))
+	}
+
+	x.builder.WriteString(";")
+
+	if stmt.Increment != nil {
+		x.builder.WriteString(" " + x.expr(stmt.Increment))
+	}
+
+	x.builder.WriteString(")")
+	x.body(stmt.Body)
+
+	return nil
+}
+
+func (x *formatter) VisitForInStmt(stmt *ForInStmt) error {
+	x.builder.WriteString("for (" + stmt.Name.Lexeme + " in " + x.expr(stmt.Iterable) + ")")
+	x.body(stmt.Body)
+
+	return nil
+}
+
+func (x *formatter) VisitFunctionStmt(stmt *FunctionStmt) error {
+	x.builder.WriteString("fun ")
+	x.function(stmt)
+
+	return nil
+}
+
+func (x *formatter) VisitReturnStmt(stmt *ReturnStmt) error {
+	x.builder.WriteString("return")
+
+	if stmt.Value != nil {
+		x.builder.WriteString(" " + x.expr(stmt.Value))
+	}
+
+	x.builder.WriteString(";")
+
+	return nil
+}
+
+func (x *formatter) VisitYieldStmt(stmt *YieldStmt) error {
+	x.builder.WriteString("yield")
+
+	if stmt.Value != nil {
+		x.builder.WriteString(" " + x.expr(stmt.Value))
+	}
+
+	x.builder.WriteString(";")
+
+	return nil
+}
+
+func (x *formatter) VisitClassStmt(stmt *ClassStmt) error {
+	x.builder.WriteString("class " + stmt.Name.Lexeme + " ")
+
+	if stmt.Superclass != nil {
+		x.builder.WriteString("< " + stmt.Superclass.Name.Lexeme + " ")
+	}
+
+	if len(stmt.Traits) > 0 {
+		traits := make([]string, 0, len(stmt.Traits))
+		for _, trait := range stmt.Traits {
+			traits = append(traits, trait.Name.Lexeme)
+		}
+
+		x.builder.WriteString("with " + strings.Join(traits, ", ") + " ")
+	}
+
+	x.members(stmt, stmt.Fields, stmt.Methods)
+
+	return nil
+}
+
+func (x *formatter) VisitTraitStmt(stmt *TraitStmt) error {
+	x.builder.WriteString("trait " + stmt.Name.Lexeme + " ")
+	x.members(stmt, nil, stmt.Methods)
+
+	return nil
+}
+
+// members writes the body of a class or trait, keeping fields and methods in
+// source order.
+func (x *formatter) members(stmt Stmt, fields []*FieldDecl, methods []*FunctionStmt) {
+	body := make([]Stmt, 0, len(fields)+len(methods))
+	for _, field := range fields {
+		member := &fieldMember{field}
+		x.spans[member] = Span{field.Name.Line, field.Name.Line}
+		body = append(body, member)
+	}
+	for _, method := range methods {
+		body = append(body, method)
+	}
+
+	sort.SliceStable(body, func(i, j int) bool {
+		return x.spans[body[i]].Start < x.spans[body[j]].Start
+	})
+
+	x.block(body, x.spans[stmt].End, x.member)
+}
+
+// endregion
+
+// region Expression visitor methods
+func (x *formatter) VisitBinaryExpr(expr *Binary) (any, error) {
+	return x.expr(expr.Left) + " " + expr.Operator.Lexeme + " " + x.expr(expr.Right), nil
+}
+
+func (x *formatter) VisitGroupingExpr(expr *Grouping) (any, error) {
+	return "(" + x.expr(expr.Expression) + ")", nil
+}
+
+func (x *formatter) VisitLiteralExpr(expr *Literal) (any, error) {
+	switch v := expr.Value.(type) {
+	case nil:
+		return "nil", nil
+	case string:
+		return `"` + v + `"`, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	return "", nil
+}
+
+func (x *formatter) VisitUnaryExpr(expr *Unary) (any, error) {
+	right := x.expr(expr.Right)
+
+	// Keep a negated negation or decrement from reading as '--'.
+	if expr.Operator.Type == Minus && strings.HasPrefix(right, "-") {
+		return "- " + right, nil
+	}
+
+	return expr.Operator.Lexeme + right, nil
+}
+
+func (x *formatter) VisitVariableExpr(expr *Variable) (any, error) {
+	return expr.Name.Lexeme, nil
+}
+
+func (x *formatter) VisitAssignExpr(expr *Assign) (any, error) {
+	return expr.Name.Lexeme + " " + expr.Operator.Lexeme + " " + x.expr(expr.Value), nil
+}
+
+func (x *formatter) VisitLogicalExpr(expr *Logical) (any, error) {
+	return x.expr(expr.Left) + " " + expr.Operator.Lexeme + " " + x.expr(expr.Right), nil
+}
+
+func (x *formatter) VisitSpawnExpr(expr *SpawnExpr) (any, error) {
+	return "spawn " + x.expr(expr.Call), nil
+}
+
+func (x *formatter) VisitCallExpr(expr *Call) (any, error) {
+	arguments := make([]string, 0, len(expr.Arguments))
+	for _, argument := range expr.Arguments {
+		arguments = append(arguments, x.expr(argument))
+	}
+
+	return x.expr(expr.Callee) + "(" + strings.Join(arguments, ", ") + ")", nil
+}
+
+func (x *formatter) VisitGetExpr(expr *Get) (any, error) {
+	if expr.Optional {
+		return x.expr(expr.Object) + "?." + expr.Name.Lexeme, nil
+	}
+
+	return x.expr(expr.Object) + "." + expr.Name.Lexeme, nil
+}
+
+func (x *formatter) VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error) {
+	return x.expr(expr.Chain), nil
+}
+
+func (x *formatter) VisitSetExpr(expr *Set) (any, error) {
+	return x.expr(expr.Object) + "." + expr.Name.Lexeme + " " + expr.Operator.Lexeme + " " + x.expr(expr.Value), nil
+}
+
+func (x *formatter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	return x.expr(expr.Object) + "[" + x.expr(expr.Index) + "]", nil
+}
+
+func (x *formatter) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	return x.expr(expr.Object) + "[" + x.expr(expr.Index) + "] " + expr.Operator.Lexeme + " " + x.expr(expr.Value), nil
+}
+
+func (x *formatter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	return x.expr(expr.Condition) + " ? " + x.expr(expr.Then) + " : " + x.expr(expr.Else), nil
+}
+
+func (x *formatter) VisitUpdateExpr(expr *UpdateExpr) (any, error) {
+	if expr.Prefix {
+		return expr.Operator.Lexeme + x.expr(expr.Target), nil
+	}
+
+	return x.expr(expr.Target) + expr.Operator.Lexeme, nil
+}
+
+// VisitMatchExpr puts each case on a line of its own, indented one level
+// more than the line the match starts on.
+func (x *formatter) VisitMatchExpr(expr *MatchExpr) (any, error) {
+	var builder strings.Builder
+
+	builder.WriteString("match (" + x.expr(expr.Value) + ") {\n")
+
+	x.indent++
+
+	for i, arm := range expr.Arms {
+		patterns := make([]string, 0, len(arm.Patterns))
+		for _, pattern := range arm.Patterns {
+			patterns = append(patterns, patternString(pattern))
+		}
+
+		builder.WriteString(strings.Repeat("  ", x.indent) + "case " + strings.Join(patterns, " | "))
+
+		if arm.Guard != nil {
+			builder.WriteString(" if " + x.expr(arm.Guard))
+		}
+
+		builder.WriteString(" => " + x.expr(arm.Body))
+
+		if i < len(expr.Arms)-1 {
+			builder.WriteString(",")
+		}
+
+		builder.WriteString("\n")
+	}
+
+	x.indent--
+
+	builder.WriteString(strings.Repeat("  ", x.indent) + "}")
+
+	return builder.String(), nil
+}
+
+func (x *formatter) VisitThisExpr(_ *ThisExpr) (any, error) {
+	return "this", nil
+}
+
+func (x *formatter) VisitSuperExpr(expr *SuperExpr) (any, error) {
+	return "super." + expr.Method.Lexeme, nil
+}
+
+// endregion
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFormatIsIdempotent formats every script that parses, checking that
+// formatting the result changes nothing and that its comments all survive,
+// in order.
+func TestFormatIsIdempotent(t *testing.T) {
+	files, err := loxFiles([]string{"testdata", "../examples", "../challenges"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := Format(string(source))
+		if err != nil {
+			continue
+		}
+
+		again, err := Format(formatted)
+		if err != nil {
+			t.Errorf("%s: formatted source doesn't parse: %v", file, err)
+			continue
+		}
+
+		if again != formatted {
+			t.Errorf("%s: formatting twice gave\n%s\nafter\n%s", file, again, formatted)
+		}
+
+		if before, after := commentTexts(t, string(source)), commentTexts(t, formatted); !reflect.DeepEqual(before, after) {
+			t.Errorf("%s: comments %q became %q", file, before, after)
+		}
+	}
+}
+
+func commentTexts(t *testing.T, source string) []string {
+	t.Helper()
+
+	tokens, err := NewTriviaScanner(source).ScanTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var comments []string
+	for _, token := range tokens {
+		if token.Type == Comment {
+			comments = append(comments, strings.TrimSpace(token.Lexeme))
+		}
+	}
+
+	return comments
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{
+			"// leading\nvar a=1;// trailing\n",
+			"// leading\nvar a = 1; // trailing\n",
+		},
+		{
+			"fun f(){\n/* block\n   comment */\nreturn 1;\n// before the brace\n}\n",
+			"fun f() {\n  /* block\n   comment */\n  return 1;\n  // before the brace\n}\n",
+		},
+		{
+			"print add(1, // first\n  2 /* second */, 3);\nprint 4;\n",
+			"print add(1, 2, 3);\n// first\n/* second */\nprint 4;\n",
+		},
+		{
+			"var x = 1 + /* inline */ 2; // after\n",
+			"var x = 1 + 2; /* inline */ // after\n",
+		},
+		{
+			"print 1;\n\n\n// alone\n\nprint 2;\n// at the end\n",
+			"print 1;\n\n// alone\n\nprint 2;\n// at the end\n",
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Format(test.source)
+		if err != nil {
+			t.Errorf("%q: %v", test.source, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%q: expected\n%s\ngot\n%s", test.source, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+func newFsModule() *moduleImpl {
+	module := &moduleImpl{name: "fs", members: map[string]any{}}
+
+	module.define("readFile", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		path, err := allowedPath(interpreter, "fs.readFile", arguments, false)
+		if err != nil {
+			return nil, err
+		}
+
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fsError("fs.readFile", err)
+		}
+
+		if err = interpreter.allocateString(len(bytes)); err != nil {
+			return nil, err
+		}
+
+		return string(bytes), nil
+	})
+	module.define("writeFile", 2, func(interpreter *Interpreter, arguments []any) (any, error) {
+		path, err := allowedPath(interpreter, "fs.writeFile", arguments, true)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := stringArgument("fs.writeFile", arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+			return nil, fsError("fs.writeFile", err)
+		}
+
+		return nil, nil
+	})
+	module.define("listDir", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		path, err := allowedPath(interpreter, "fs.listDir", arguments, false)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fsError("fs.listDir", err)
+		}
+
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		sort.Strings(names)
+
+		if err = interpreter.allocateList(len(names)); err != nil {
+			return nil, err
+		}
+
+		elements := make([]any, 0, len(names))
+		for _, name := range names {
+			elements = append(elements, name)
+		}
+
+		return newList(elements), nil
+	})
+	module.define("exists", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		path, err := allowedPath(interpreter, "fs.exists", arguments, false)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		if err != nil {
+			return nil, fsError("fs.exists", err)
+		}
+
+		return true, nil
+	})
+
+	return module
+}
+
+// allowedPath returns the path given as first argument to the native called
+// name, with its links resolved, if the interpreter's policy lets scripts use
+// it.
+func allowedPath(interpreter *Interpreter, name string, arguments []any, write bool) (string, error) {
+	path, err := stringArgument(name, arguments, 0)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, ok := interpreter.Policy.allowsPath(path, write)
+	if !ok {
+		return "", nativeErrorf("%s: access to '%s' denied", name, path)
+	}
+
+	return resolved, nil
+}
+
+// fsError reports err without the operation and path Go prefixes it with,
+// which the script already knows.
+func fsError(name string, err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+
+	return nativeErrorf("%s: %s", name, err)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// errGeneratorClosed unwinds the body of a generator nothing refers to any
+// more.
+var errGeneratorClosed = errors.New("generator closed")
+
+// GeneratorImpl is what calling a generator function returns. Its body runs
+// on a goroutine of its own, on an interpreter forked from the one that first
+// asks for a value, and the two take turns: the caller waits while the body
+// runs up to its next yield, and the body waits until asked for another
+// value. The environment the body suspends in is kept by that goroutine, so
+// resuming it needs no bookkeeping.
+type GeneratorImpl struct {
+	function    *FunctionImpl
+	environment *Environment
+	state       *generatorState
+
+	// turn is held by the caller advancing the body, and guards the fields
+	// below, as tasks may share x. Unlike a mutex, waiting for it can be
+	// interrupted.
+	turn     chan struct{}
+	started  bool
+	finished bool
+	buffered bool // value holds a yielded value not yet returned by next
+	value    any
+}
+
+// generatorState is the part of a generator its goroutine holds on to, which
+// leaves the GeneratorImpl free to be garbage collected. Started generators
+// are also registered on the budget of the program, which closes the ones
+// still suspended when it ends: one a global refers to is never collected,
+// since its goroutine keeps the globals alive.
+type generatorState struct {
+	resume      chan struct{}
+	results     chan generatorResult
+	budget      *budget
+	interpreter *Interpreter // runs the body; only touched while it's suspended
+
+	mutex  sync.Mutex // guards the fields below, as the program or a finalizer may close x
+	closed bool
+	caller *generatorState // the generator whose body runs x, if any, while x runs
+}
+
+type generatorResult struct {
+	value any
+	done  bool
+	err   error
+}
+
+func newGenerator(function *FunctionImpl, environment *Environment) *GeneratorImpl {
+	generator := &GeneratorImpl{
+		function:    function,
+		environment: environment,
+		turn:        make(chan struct{}, 1),
+		state: &generatorState{
+			resume:  make(chan struct{}),
+			results: make(chan generatorResult),
+		},
+	}
+
+	runtime.SetFinalizer(generator, (*GeneratorImpl).close)
+
+	return generator
+}
+
+// Get returns the method of x called name, bound to x.
+func (x *GeneratorImpl) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "hasNext":
+		return &nativeFunction{"hasNext", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			_, ok, err := x.take(interpreter, false)
+
+			return ok, err
+		}}, nil
+	case "next":
+		return &nativeFunction{"next", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			value, ok, err := x.take(interpreter, true)
+			if err == nil && !ok {
+				return nil, nativeErrorf("generator is exhausted")
+			}
+
+			return value, err
+		}}, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+func (x *GeneratorImpl) String() string {
+	return "<generator " + x.function.declaration.Name.Lexeme + ">"
+}
+
+// take returns the next value of x and whether there is one, leaving it to
+// be taken again unless consume is set.
+func (x *GeneratorImpl) take(interpreter *Interpreter, consume bool) (any, bool, error) {
+	// The body waiting on interpreter can't go on until it gets a value,
+	// whether it asks itself or through other generators.
+	if x.state.runs(interpreter) {
+		return nil, false, nativeErrorf("generator is already running")
+	}
+
+	select {
+	case x.turn <- struct{}{}:
+	case <-interpreter.budget.interrupted:
+		return nil, false, interpreter.budget.interruption()
+	}
+
+	defer func() {
+		<-x.turn
+	}()
+
+	if err := x.advance(interpreter); err != nil {
+		return nil, false, err
+	}
+
+	if !x.buffered {
+		return nil, false, nil
+	}
+
+	if consume {
+		x.buffered = false
+	}
+
+	return x.value, true, nil
+}
+
+// advance runs the body up to its next yield, unless a yielded value is
+// already waiting or the body has finished.
+func (x *GeneratorImpl) advance(interpreter *Interpreter) error {
+	if x.buffered || x.finished {
+		return nil
+	}
+
+	x.state.setCaller(interpreter.generator)
+	defer x.state.setCaller(nil)
+
+	if x.started {
+		if !x.state.wake(interpreter) {
+			return nativeErrorf("generator was stopped before it finished")
+		}
+	} else {
+		x.started = true
+
+		x.state.interpreter = interpreter.fork()
+		x.state.interpreter.generator = x.state
+		x.state.interpreter.depth = interpreter.depth
+
+		x.state.budget = interpreter.budget
+		x.state.budget.addGenerator(x.state)
+
+		go x.state.run(x.function.declaration.Body, x.environment)
+	}
+
+	var result generatorResult
+
+	select {
+	case result = <-x.state.results:
+	case <-interpreter.budget.interrupted:
+		// The body is left for good, since the value it's about to hand
+		// over has nowhere to go.
+		x.close()
+		x.finished = true
+
+		return interpreter.budget.interruption()
+	}
+
+	if result.done {
+		x.finished = true
+		x.state.budget.removeGenerator(x.state)
+
+		return result.err
+	}
+
+	x.value = result.value
+	x.buffered = true
+
+	return nil
+}
+
+// close ends the goroutine of a generator that was abandoned halfway.
+func (x *GeneratorImpl) close() {
+	if x.started && !x.finished {
+		x.state.close()
+		x.state.budget.removeGenerator(x.state)
+	}
+}
+
+func (x *generatorState) setCaller(caller *generatorState) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.caller = caller
+}
+
+// runs reports whether interpreter runs the body of x, or of a generator
+// whose body x runs, and so on.
+func (x *generatorState) runs(interpreter *Interpreter) bool {
+	for state := interpreter.generator; state != nil; {
+		if state == x {
+			return true
+		}
+
+		state.mutex.Lock()
+		caller := state.caller
+		state.mutex.Unlock()
+
+		state = caller
+	}
+
+	return false
+}
+
+// wake resumes the suspended body for caller, unless x has been closed, and
+// reports whether it did.
+func (x *generatorState) wake(caller *Interpreter) bool {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if x.closed {
+		return false
+	}
+
+	// Resolve replaces the map of resolved variables as the REPL reads more
+	// code, which the body may call into by now. The body's calls nest in
+	// the caller's, since the caller waits for them.
+	x.interpreter.locals = caller.locals
+	x.interpreter.depth = caller.depth
+
+	x.resume <- struct{}{}
+
+	return true
+}
+
+// close makes the suspended body unwind, once.
+func (x *generatorState) close() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if !x.closed {
+		x.closed = true
+		close(x.resume)
+	}
+}
+
+func (x *generatorState) run(body []Stmt, environment *Environment) {
+	err := x.interpreter.runGenerator(body, environment)
+	if errors.Is(err, errGeneratorClosed) {
+		return
+	}
+
+	select {
+	case x.results <- generatorResult{done: true, err: err}:
+	case <-x.resume:
+	}
+}
+
+// yield hands value to the caller and waits to be resumed. The caller may
+// have been interrupted and closed x instead of taking value.
+func (x *generatorState) yield(value any) error {
+	select {
+	case x.results <- generatorResult{value: value}:
+	case <-x.resume:
+		return errGeneratorClosed
+	}
+
+	if _, ok := <-x.resume; !ok {
+		return errGeneratorClosed
+	}
+
+	return nil
+}
+
+func (x *Interpreter) runGenerator(body []Stmt, environment *Environment) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(FunctionReturn); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	return x.executeBlock(body, environment)
+}
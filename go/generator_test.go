@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestAbandonedGenerators checks that generators dropped before finishing
+// don't leave their goroutines behind.
+func TestAbandonedGenerators(t *testing.T) {
+	const program = `
+fun naturals() {
+  var i = 0;
+  while (true) {
+    yield i;
+    i = i + 1;
+  }
+}
+
+for (var i = 0; i < 100; i = i + 1) {
+  var g = naturals();
+  g.next();
+  g.next();
+}
+`
+
+	before := runtime.NumGoroutine()
+
+	statements, err := parse(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout}).Init()
+	if err := interpreter.Resolve(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := interpreter.Interpret(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finalizers run on a goroutine of their own after a collection, so give
+	// them a few chances.
+	for i := 0; i < 50; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+
+		if runtime.NumGoroutine() <= before+10 {
+			return
+		}
+	}
+
+	t.Errorf("%d goroutines left running, started with %d", runtime.NumGoroutine(), before)
+}
+
+// TestGeneratorsInGlobals checks that generators a global still refers to
+// when the program ends don't keep their goroutines, and with them the
+// interpreter, alive.
+func TestGeneratorsInGlobals(t *testing.T) {
+	const program = `
+fun g() {
+  yield 1;
+  yield 2;
+}
+
+var x = g();
+x.next();
+`
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		var stdout bytes.Buffer
+
+		interpreter := (&Interpreter{Stdout: &stdout}).Init()
+		if err := runSource(interpreter, program); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		if runtime.NumGoroutine() <= before+10 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("%d goroutines left running, started with %d", runtime.NumGoroutine(), before)
+}
+
+// TestGeneratorStoppedWithProgram checks that resuming a generator the
+// previous program left suspended fails cleanly, unless the interpreter is
+// interactive.
+func TestGeneratorStoppedWithProgram(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Stderr: &stdout}).Init()
+	if err := runSource(interpreter, `fun g() { yield 1; yield 2; } var x = g(); print x.next();`); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runSource(interpreter, `x.next();`)
+
+	var runtimeErr RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "generator was stopped before it finished" {
+		t.Errorf("expected the generator to have been stopped, got %v", err)
+	}
+}
+
+func TestGeneratorResumedInteractively(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Interactive: true}).Init()
+
+	lines := []string{
+		`fun g() { yield 1; yield 2; }`,
+		`var x = g();`,
+		`print x.next();`,
+		`print x.next();`,
+	}
+
+	for _, line := range lines {
+		if err := runSource(interpreter, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stdout.String() != "1\n2\n" {
+		t.Errorf("expected the generator to go on across programs, got %q", stdout.String())
+	}
+}
+
+// TestGeneratorSeesLaterCode checks that a generator resumed in the REPL can
+// call functions resolved after it started.
+func TestGeneratorSeesLaterCode(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Interactive: true}).Init()
+
+	lines := []string{
+		`var h = clock;`,
+		`fun gen() { while (true) yield h(); }`,
+		`var g = gen(); g.next();`,
+		`{ var b = 2; fun cb2() { return b; } h = cb2; }`,
+		`print h();`,
+		`print g.next();`,
+	}
+
+	for _, line := range lines {
+		if err := runSource(interpreter, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stdout.String() != "2\n2\n" {
+		t.Errorf("expected the generator to call the new function, got %q", stdout.String())
+	}
+}
+
+// TestGeneratorsResumingEachOther checks that generators whose bodies resume
+// each other fail instead of waiting on each other forever.
+func TestGeneratorsResumingEachOther(t *testing.T) {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- interpretWithLimits(context.Background(), Limits{Timeout: 200 * time.Millisecond}, `
+var a;
+var b;
+fun ga() { yield b.next(); }
+fun gb() { yield a.next(); }
+a = ga();
+b = gb();
+print a.next();
+`)
+	}()
+
+	select {
+	case err := <-done:
+		var runtimeErr RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Message != "generator is already running" {
+			t.Errorf("expected the generators to fail, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("the generators hung")
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Golden tests are Lox scripts annotated with the output they should produce,
+// in the style of the Crafting Interpreters test suite:
+//
+//	print 1 + 2; // expect: 3
+//	nil();       // expect runtime error: can only call functions and classes
+//	var a = ;    // Error at ';': expect expression
+//	var n: Number = "one"; // expect type error: can't assign String to 'n' of type Number
+//
+// Scripts run with empty input. Output lines must match the expect comments
+// in order. A runtime error is expected on the line its comment is on, and a
+// compile error on that line too unless it says otherwise with a "[line N]"
+// prefix. Scripts expecting type errors are run through the Checker as well,
+// and must get exactly those.
+var (
+	expectOutputPattern       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectRuntimeErrorPattern = regexp.MustCompile(`// expect runtime error: (.+)`)
+	expectTypeErrorPattern    = regexp.MustCompile(`// expect type error: (.+)`)
+	expectCompileErrorPattern = regexp.MustCompile(`// (\[line (\d+)\] )?(Error.*)`)
+)
+
+type goldenTest struct {
+	path           string
+	output         []goldenLine
+	runtimeError   *goldenLine
+	typeErrors     []goldenLine
+	compileErrors  []string
+	hasExpectation bool
+}
+
+// goldenLine is an expected line of output or error message and the source
+// line it was declared on.
+type goldenLine struct {
+	line int
+	text string
+}
+
+func loadGoldenTest(path string) (*goldenTest, string, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	test := &goldenTest{path: path}
+
+	for i, text := range strings.Split(string(source), "\n") {
+		line := i + 1
+
+		if match := expectOutputPattern.FindStringSubmatch(text); match != nil {
+			test.output = append(test.output, goldenLine{line, strings.TrimRight(match[1], "\r")})
+			test.hasExpectation = true
+		} else if match = expectRuntimeErrorPattern.FindStringSubmatch(text); match != nil {
+			test.runtimeError = &goldenLine{line, strings.TrimRight(match[1], "\r")}
+			test.hasExpectation = true
+		} else if match = expectTypeErrorPattern.FindStringSubmatch(text); match != nil {
+			test.typeErrors = append(test.typeErrors, goldenLine{line, strings.TrimRight(match[1], "\r")})
+			test.hasExpectation = true
+		} else if match = expectCompileErrorPattern.FindStringSubmatch(text); match != nil {
+			if match[2] != "" {
+				line, _ = strconv.Atoi(match[2])
+			}
+
+			message := fmt.Sprintf("[line %d] %s", line, strings.TrimRight(match[3], "\r"))
+			test.compileErrors = append(test.compileErrors, message)
+			test.hasExpectation = true
+		}
+	}
+
+	return test, string(source), nil
+}
+
+// runGoldenTest runs the script at path on a fresh interpreter and returns
+// how its behaviour differs from the annotations, if at all. Scripts without
+// any annotation aren't tests and are reported as skipped.
+func runGoldenTest(path string) (skipped bool, failures []string, err error) {
+	test, source, err := loadGoldenTest(path)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if !test.hasExpectation {
+		return true, nil, nil
+	}
+
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Stderr: io.Discard, Stdin: strings.NewReader("")}).Init()
+	err = runSource(interpreter, source)
+
+	failures = test.check(stdout.String(), err)
+
+	if len(test.typeErrors) > 0 {
+		failures = append(failures, test.checkTypes(source)...)
+	}
+
+	return false, failures, nil
+}
+
+// checkTypes compares the errors the Checker finds in source with the
+// expected type errors, in order. Scripts that don't compile are left to
+// check to report.
+func (x *goldenTest) checkTypes(source string) []string {
+	statements, err := parse(source)
+	if err != nil {
+		return nil
+	}
+
+	if _, err = NewResolver().Resolve(statements); err != nil {
+		return nil
+	}
+
+	var failures []string
+
+	errs := NewChecker().Check(statements)
+
+	for i, expected := range x.typeErrors {
+		if i >= len(errs) {
+			failures = append(failures, fmt.Sprintf("line %d: missing expected type error '%s'", expected.line, expected.text))
+			continue
+		}
+
+		got := errs[i].(*CompileError)
+		if got.Message != expected.text || got.Line != expected.line {
+			failures = append(failures, fmt.Sprintf("line %d: expected type error '%s', got '%s' at line %d",
+				expected.line, expected.text, got.Message, got.Line))
+		}
+	}
+
+	if len(errs) > len(x.typeErrors) {
+		for _, extra := range errs[len(x.typeErrors):] {
+			got := extra.(*CompileError)
+			failures = append(failures, fmt.Sprintf("unexpected type error at line %d: %s", got.Line, got.Message))
+		}
+	}
+
+	return failures
+}
+
+func (x *goldenTest) check(stdout string, err error) []string {
+	var failures []string
+
+	output := strings.Split(stdout, "\n")
+	if output[len(output)-1] == "" {
+		output = output[:len(output)-1]
+	}
+
+	for i, expected := range x.output {
+		if i >= len(output) {
+			failures = append(failures, fmt.Sprintf("line %d: missing expected output '%s'", expected.line, expected.text))
+		} else if output[i] != expected.text {
+			failures = append(failures, fmt.Sprintf("line %d: expected output '%s', got '%s'", expected.line, expected.text, output[i]))
+		}
+	}
+
+	if len(output) > len(x.output) {
+		for _, extra := range output[len(x.output):] {
+			failures = append(failures, fmt.Sprintf("unexpected output '%s'", extra))
+		}
+	}
+
+	var runtimeErr RuntimeError
+	var compileErr *CompileError
+
+	switch {
+	case errors.As(err, &runtimeErr):
+		if x.runtimeError == nil {
+			failures = append(failures, fmt.Sprintf("unexpected runtime error at line %d: %s", runtimeErr.Token.Line, runtimeErr.Message))
+		} else if runtimeErr.Message != x.runtimeError.text || runtimeErr.Token.Line != x.runtimeError.line {
+			failures = append(failures, fmt.Sprintf("line %d: expected runtime error '%s', got '%s' at line %d",
+				x.runtimeError.line, x.runtimeError.text, runtimeErr.Message, runtimeErr.Token.Line))
+		}
+	case errors.As(err, &compileErr):
+		got := strings.TrimSpace(compileErr.Error())
+
+		// Only the first compile error is reported, so that's the only one
+		// that can be checked.
+		if len(x.compileErrors) == 0 {
+			failures = append(failures, "unexpected compile error: "+got)
+		} else if got != x.compileErrors[0] {
+			failures = append(failures, fmt.Sprintf("expected compile error '%s', got '%s'", x.compileErrors[0], got))
+		}
+	case err != nil:
+		failures = append(failures, "unexpected error: "+err.Error())
+	}
+
+	if x.runtimeError != nil && !errors.As(err, &runtimeErr) {
+		failures = append(failures, fmt.Sprintf("line %d: missing expected runtime error '%s'", x.runtimeError.line, x.runtimeError.text))
+	}
+
+	if len(x.compileErrors) > 0 && !errors.As(err, &compileErr) {
+		failures = append(failures, "missing expected compile error '"+x.compileErrors[0]+"'")
+	}
+
+	return failures
+}
+
+// testCommand runs the golden tests among the .lox files in paths, printing
+// a line per test and the failures of those that didn't pass.
+func testCommand(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return 64
+	}
+
+	files, err := loxFiles(args)
+	if err != nil {
+		fmt.Println(err)
+		return 66
+	}
+
+	passed, failed, skipped := 0, 0, 0
+
+	for _, file := range files {
+		skip, failures, err := runGoldenTest(file)
+		if err != nil {
+			fmt.Println(err)
+			return 66
+		}
+
+		switch {
+		case skip:
+			skipped++
+			fmt.Printf("SKIP %s (no expectations)\n", file)
+		case len(failures) > 0:
+			failed++
+			fmt.Printf("FAIL %s\n", file)
+
+			for _, failure := range failures {
+				fmt.Printf("     %s\n", failure)
+			}
+		default:
+			passed++
+			fmt.Printf("PASS %s\n", file)
+		}
+	}
+
+	fmt.Printf("%d passed, %d failed, %d skipped\n", passed, failed, skipped)
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGolden runs the annotated scripts in testdata, examples and challenges.
+func TestGolden(t *testing.T) {
+	files, err := loxFiles([]string{"testdata", "../examples", "../challenges"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		file := file
+
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			skipped, failures, err := runGoldenTest(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if skipped {
+				t.Skip("no expectations")
+			}
+
+			for _, failure := range failures {
+				t.Error(failure)
+			}
+		})
+	}
+}
+
+func TestGoldenReportsMismatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mismatch.lox")
+
+	source := `print 1;   // expect: 2
+print "a"; // expect runtime error: boom
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped, failures, err := runGoldenTest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if skipped {
+		t.Fatal("annotated script was skipped")
+	}
+
+	expected := []string{
+		"line 1: expected output '2', got '1'",
+		"unexpected output 'a'",
+		"line 2: missing expected runtime error 'boom'",
+	}
+
+	if len(failures) != len(expected) {
+		t.Fatalf("expected failures %q, got %q", expected, failures)
+	}
+
+	for i := range expected {
+		if failures[i] != expected[i] {
+			t.Errorf("expected failure %q, got %q", expected[i], failures[i])
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// reader returns the buffered reader over Stdin that all reads share, so
+// none of them loses input another has buffered.
+func (x *Interpreter) reader() *bufio.Reader {
+	return x.stdin
+}
+
+// readLine reads the next line of input without its line ending. It reports
+// false once the input is exhausted.
+func (x *Interpreter) readLine() (string, bool, error) {
+	x.console.input.Lock()
+	defer x.console.input.Unlock()
+
+	line, err := x.reader().ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		if line == "" {
+			return "", false, nil
+		}
+	} else if err != nil {
+		return "", false, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	return line, true, nil
+}
+
+// input([prompt]) writes the prompt, if any, and reads a line; nil at the end
+// of the input.
+func funInput(interpreter *Interpreter, arguments []any) (any, error) {
+	if len(arguments) > 1 {
+		return nil, nativeErrorf("'input' expects at most 1 argument but got %d", len(arguments))
+	}
+
+	if len(arguments) == 1 {
+		prompt, err := stringArgument("input", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		interpreter.console.output.Lock()
+		_, _ = fmt.Fprint(interpreter.Stdout, prompt)
+		interpreter.console.output.Unlock()
+	}
+
+	return funReadLine(interpreter, nil)
+}
+
+func funReadLine(interpreter *Interpreter, _ []any) (any, error) {
+	line, ok, err := interpreter.readLine()
+	if err != nil {
+		return nil, nativeErrorf("readLine: %s", err)
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	if err = interpreter.allocateString(len(line)); err != nil {
+		return nil, err
+	}
+
+	return line, nil
+}
+
+// readAll returns the rest of the input, or nil if there is none.
+func funReadAll(interpreter *Interpreter, _ []any) (any, error) {
+	interpreter.console.input.Lock()
+	bytes, err := io.ReadAll(interpreter.reader())
+	interpreter.console.input.Unlock()
+	if err != nil {
+		return nil, nativeErrorf("readAll: %s", err)
+	}
+
+	if len(bytes) == 0 {
+		return nil, nil
+	}
+
+	if err = interpreter.allocateString(len(bytes)); err != nil {
+		return nil, err
+	}
+
+	return string(bytes), nil
+}
+
+// readLines returns the remaining lines of input as a list.
+func funReadLines(interpreter *Interpreter, _ []any) (any, error) {
+	lines := &ListImpl{elements: []any{}}
+
+	for {
+		line, ok, err := interpreter.readLine()
+		if err != nil {
+			return nil, nativeErrorf("readLines: %s", err)
+		}
+
+		if !ok {
+			return lines, nil
+		}
+
+		if err = interpreter.allocate(valueSize + stringSize + len(line)); err != nil {
+			return nil, err
+		}
+
+		lines.elements = append(lines.elements, line)
+	}
+}
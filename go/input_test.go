@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInputNatives(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{
+		Stdout: &stdout,
+		Stderr: io.Discard,
+		Stdin:  strings.NewReader("first\r\nsecond\nthird\nfourth\nrest\nof it"),
+	}).Init()
+
+	err := runSource(interpreter, `
+		print input("? ");
+		print readLine();
+		var lines = list();
+		var line;
+		while ((line = readLine()) != "fourth") lines.push(line);
+		print lines;
+		print readAll();
+		print readLine();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "? first\nsecond\n[third]\nrest\nof it\nnil\n"
+	if stdout.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestReadLinesKeepsUnterminatedLastLine(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Stdin: strings.NewReader("a\n\nb")}).Init()
+
+	if err := runSource(interpreter, `print readLines();`); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "[a, , b]\n"; stdout.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, stdout.String())
+	}
+}
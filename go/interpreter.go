@@ -1,39 +1,149 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"math"
+	"os"
 )
 
 type Interpreter struct {
+	// Stdout receives the output of print statements. It defaults to
+	// os.Stdout.
+	Stdout io.Writer
+	// Stderr receives runtime error reports. It defaults to os.Stderr.
+	Stderr io.Writer
+	// Stdin is what the input natives read from. It defaults to os.Stdin.
+	Stdin io.Reader
+	// Policy grants scripts access to the file system, the environment and
+	// the process. Nil denies it all.
+	Policy *Policy
+	// Args are the script arguments, available to scripts as os.args.
+	Args []string
+	// Limits bound the work each Interpret call may do.
+	Limits Limits
+	// Interactive marks an interpreter whose programs build on each other,
+	// like the lines of the REPL: declarations may replace global constants,
+	// and generators left suspended when one ends can be resumed by the next.
+	Interactive bool
+
+	stdin       *bufio.Reader
+	console     *console
 	globals     *Environment
 	environment *Environment
 	locals      map[Expr]int
+	debugger    debugHook
+
+	budget    *budget
+	depth     int
+	generator *generatorState // set on interpreters running a generator's body
+}
+
+// debugHook lets a debugger follow execution: it sees every statement before
+// it runs and every Lox function call, and can abort the program by returning
+// an error.
+type debugHook interface {
+	beforeStatement(interpreter *Interpreter, stmt Stmt) error
+	enterFunction(function *FunctionImpl, environment *Environment)
+	exitFunction(function *FunctionImpl)
 }
 
 func (x *Interpreter) Init() *Interpreter {
+	if x.Stdout == nil {
+		x.Stdout = os.Stdout
+	}
+	if x.Stderr == nil {
+		x.Stderr = os.Stderr
+	}
+	if x.Stdin == nil {
+		x.Stdin = os.Stdin
+	}
+
 	x.globals = &Environment{
 		values: map[string]any{},
 	}
 
 	// native functions
 	x.globals.Define("clock", &funClock{})
+	x.globals.Define("str", &nativeFunction{"str", 1, funStr})
+	x.globals.Define("type", &nativeFunction{"type", 1, funType})
+	x.globals.Define("fields", &nativeFunction{"fields", 1, funFields})
+	x.globals.Define("hasField", &nativeFunction{"hasField", 2, funHasField})
+	x.globals.Define("getField", &nativeFunction{"getField", 2, funGetField})
+	x.globals.Define("setField", &nativeFunction{"setField", 3, funSetField})
+	x.globals.Define("freeze", &nativeFunction{"freeze", 1, funFreeze})
+	x.globals.Define("methods", &nativeFunction{"methods", 1, funMethods})
+	x.globals.Define("superclass", &nativeFunction{"superclass", 1, funSuperclass})
+	x.globals.Define("arity", &nativeFunction{"arity", 1, funArity})
+	x.globals.Define("list", &nativeFunction{"list", -1, funList})
+	x.globals.Define("map", &nativeFunction{"map", 0, funMap})
+	x.globals.Define("input", &nativeFunction{"input", -1, funInput})
+	x.globals.Define("readLine", &nativeFunction{"readLine", 0, funReadLine})
+	x.globals.Define("readAll", &nativeFunction{"readAll", 0, funReadAll})
+	x.globals.Define("readLines", &nativeFunction{"readLines", 0, funReadLines})
+	x.globals.Define("channel", &nativeFunction{"channel", -1, funChannel})
+	x.globals.Define("select", &nativeFunction{"select", -1, funSelect})
+	x.globals.Define("math", newMathModule())
+	x.globals.Define("time", newTimeModule())
+	x.globals.Define("fs", newFsModule())
+	x.globals.Define("os", newOsModule(x.Args))
+	x.globals.Define("json", newJsonModule())
 
 	x.environment = x.globals
 	x.locals = make(map[Expr]int)
+	x.budget = &budget{interrupted: make(chan struct{})}
+	x.stdin = bufio.NewReader(x.Stdin)
+	x.console = &console{}
 
 	return x
 }
 
+// fork returns an interpreter to run code of the same program on another
+// goroutine: it shares the configuration, globals, resolved variables, input
+// and budget of x, and has a call stack of its own.
+func (x *Interpreter) fork() *Interpreter {
+	return &Interpreter{
+		Stdout:      x.Stdout,
+		Stderr:      x.Stderr,
+		Stdin:       x.Stdin,
+		Policy:      x.Policy,
+		Args:        x.Args,
+		Limits:      x.Limits,
+		Interactive: x.Interactive,
+		stdin:       x.stdin,
+		console:     x.console,
+		globals:     x.globals,
+		environment: x.globals,
+		locals:      x.locals,
+		budget:      x.budget,
+	}
+}
+
 func (x *Interpreter) Interpret(statements []Stmt) error {
+	return x.InterpretContext(context.Background(), statements)
+}
+
+// InterpretContext runs statements until they finish, fail, exceed the
+// interpreter's Limits or ctx is done, in which case it returns ctx.Err().
+func (x *Interpreter) InterpretContext(ctx context.Context, statements []Stmt) error {
 	var err error
 
+	x.budget.steps.Store(0)
+	x.budget.allocated.Store(0)
+	x.budget.start()
+	x.depth = 0
+
+	defer x.watch(ctx)()
+	defer x.finishTasks()
+
 	for _, stmt := range statements {
 		if err = x.execute(stmt); err != nil {
 			var rErr RuntimeError
 			if errors.As(err, &rErr) {
-				runtimeError(rErr)
+				x.runtimeError(rErr)
 			}
 
 			return err
@@ -43,8 +153,27 @@ func (x *Interpreter) Interpret(statements []Stmt) error {
 	return err
 }
 
-func (x *Interpreter) Resolve(expr Expr, depth int) {
-	x.locals[expr] = depth
+// Resolve prepares statements to be interpreted, reporting the errors the
+// resolver finds in them.
+func (x *Interpreter) Resolve(statements []Stmt) error {
+	locals, err := NewResolver().Resolve(statements)
+	if err != nil {
+		return err
+	}
+
+	// The map is replaced rather than updated, so interpreters forked from
+	// this one can go on reading the old one.
+	merged := make(map[Expr]int, len(x.locals)+len(locals))
+	for expr, depth := range x.locals {
+		merged[expr] = depth
+	}
+	for expr, depth := range locals {
+		merged[expr] = depth
+	}
+
+	x.locals = merged
+
+	return nil
 }
 
 // region Expression visitor methods
@@ -54,38 +183,56 @@ func (x *Interpreter) VisitBinaryExpr(expr *Binary) (any, error) {
 		return nil, err
 	}
 
+	// The built-in type names aren't variables, so 'is' tests for them the
+	// way patterns do.
+	if name, ok := expr.Right.(*Variable); ok && expr.Operator.Type == Is {
+		if _, builtin := patternTypes[name.Name.Lexeme]; builtin {
+			return x.hasType(left, name)
+		}
+	}
+
 	right, err := x.evaluate(expr.Right)
 	if err != nil {
 		return nil, err
 	}
 
-	switch expr.Operator.Type {
+	return x.binary(expr.Operator, left, right)
+}
+
+// binary applies an arithmetic, comparison or equality operator, or the
+// method overloading it, to left and right.
+func (x *Interpreter) binary(operator Token, left any, right any) (any, error) {
+	if method := x.operator(left, binaryOperators[operator.Type]); method != nil {
+		return x.call(method, []any{right}, operator)
+	}
+
+	switch operator.Type {
 	case Greater:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) > right.(float64), nil
 	case GreaterEqual:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) >= right.(float64), nil
 	case Less:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) < right.(float64), nil
 	case LessEqual:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) <= right.(float64), nil
 	case Minus:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
@@ -99,27 +246,50 @@ func (x *Interpreter) VisitBinaryExpr(expr *Binary) (any, error) {
 
 		if leftVal, ok := left.(string); ok {
 			if rightVal, ok := right.(string); ok {
+				if err := x.allocateString(len(leftVal) + len(rightVal)); err != nil {
+					return nil, err
+				}
+
 				return leftVal + rightVal, nil
 			}
 		}
 
-		return nil, RuntimeError{"operands must be two numbers or two strings", expr.Operator}
+		return nil, RuntimeError{"operands must be two numbers or two strings", operator}
 	case Slash:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) / right.(float64), nil
 	case Star:
-		if err := x.checkNumberOperands(expr.Operator, left, right); err != nil {
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
 			return nil, err
 		}
 
 		return left.(float64) * right.(float64), nil
+	case Percent:
+		if err := x.checkNumberOperands(operator, left, right); err != nil {
+			return nil, err
+		}
+
+		return math.Mod(left.(float64), right.(float64)), nil
+	case Is:
+		instance, ok := left.(*InstanceImpl)
+
+		switch right := right.(type) {
+		case *ClassImpl:
+			return ok && instance.klass.isSubclassOf(right), nil
+		case *TraitImpl:
+			return ok && instance.klass.hasTrait(right), nil
+		}
+
+		return nil, RuntimeError{"right operand of 'is' must be a class or trait", operator}
 	case BangEqual:
-		return !x.isEqual(left, right), nil
+		equal, err := x.isEqual(left, right)
+
+		return !equal, err
 	case EqualEqual:
-		return x.isEqual(left, right), nil
+		return x.isEqual(left, right)
 	}
 
 	return nil, nil
@@ -143,6 +313,10 @@ func (x *Interpreter) VisitUnaryExpr(expr *Unary) (any, error) {
 	case Bang:
 		return !x.isTruthy(right), nil
 	case Minus:
+		if method := x.operator(right, "__neg"); method != nil {
+			return x.call(method, nil, expr.Operator)
+		}
+
 		if err := x.checkNumberOperand(expr.Operator, right); err != nil {
 			return nil, err
 		}
@@ -158,20 +332,29 @@ func (x *Interpreter) VisitVariableExpr(expr *Variable) (any, error) {
 }
 
 func (x *Interpreter) VisitAssignExpr(expr *Assign) (any, error) {
+	var current any
+	if expr.Operator.Type != Equal {
+		var err error
+		if current, err = x.lookupVariable(expr.Name, expr); err != nil {
+			return nil, err
+		}
+	}
+
 	value, err := x.evaluate(expr.Value)
 	if err != nil {
 		return nil, err
 	}
 
-	if distance, ok := x.locals[expr]; ok {
-		x.environment.AssignAt(distance, expr.Name, value)
-	} else {
-		err = x.globals.Assign(expr.Name, value)
-		if err != nil {
+	if expr.Operator.Type != Equal {
+		if value, err = x.compound(expr.Operator, current, value); err != nil {
 			return nil, err
 		}
 	}
 
+	if err = x.assignVariable(expr.Name, expr, value); err != nil {
+		return nil, err
+	}
+
 	return value, nil
 }
 
@@ -181,11 +364,16 @@ func (x *Interpreter) VisitLogicalExpr(expr *Logical) (any, error) {
 		return nil, err
 	}
 
-	if expr.Operator.Type == Or {
+	switch expr.Operator.Type {
+	case Or:
 		if x.isTruthy(left) {
 			return left, nil
 		}
-	} else {
+	case QuestionQuestion:
+		if left != nil {
+			return left, nil
+		}
+	default:
 		if !x.isTruthy(left) {
 			return left, nil
 		}
@@ -194,44 +382,144 @@ func (x *Interpreter) VisitLogicalExpr(expr *Logical) (any, error) {
 	return x.evaluate(expr.Right)
 }
 
+func (x *Interpreter) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	condition, err := x.evaluate(expr.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	if x.isTruthy(condition) {
+		return x.evaluate(expr.Then)
+	}
+
+	return x.evaluate(expr.Else)
+}
+
 func (x *Interpreter) VisitCallExpr(expr *Call) (any, error) {
-	callee, err := x.evaluate(expr.Callee)
+	fn, arguments, err := x.evaluateCall(expr)
 	if err != nil {
 		return nil, err
 	}
 
+	return x.call(fn, arguments, expr.Paren)
+}
+
+// evaluateCall evaluates the callee and arguments of expr and checks they
+// make a valid call.
+func (x *Interpreter) evaluateCall(expr *Call) (Callable, []any, error) {
+	callee, err := x.evaluate(expr.Callee)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var arguments []any
 	for _, arg := range expr.Arguments {
 		argument, err := x.evaluate(arg)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		arguments = append(arguments, argument)
 	}
 
+	if method := x.operator(callee, "__call"); method != nil {
+		callee = method
+	}
+
 	fn, ok := callee.(Callable)
 	if !ok {
-		return nil, RuntimeError{
+		return nil, nil, RuntimeError{
 			Message: "can only call functions and classes",
 			Token:   expr.Paren,
 		}
 	}
 
-	return fn.Call(x, arguments)
+	if arity := fn.Arity(); arity >= 0 && len(arguments) != arity {
+		return nil, nil, RuntimeError{
+			Message: fmt.Sprintf("expected %d arguments but got %d", arity, len(arguments)),
+			Token:   expr.Paren,
+		}
+	}
+
+	return fn, arguments, nil
+}
+
+// call calls fn, reporting errors from natives at token.
+func (x *Interpreter) call(fn Callable, arguments []any, token Token) (any, error) {
+	if err := x.enterCall(); err != nil {
+		return nil, err
+	}
+	defer x.exitCall()
+
+	value, err := fn.Call(x, arguments)
+	if err != nil {
+		return nil, reportAt(err, token)
+	}
+
+	return value, nil
+}
+
+func (x *Interpreter) VisitSpawnExpr(expr *SpawnExpr) (any, error) {
+	fn, arguments, err := x.evaluateCall(expr.Call)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = x.allocate(taskSize); err != nil {
+		return nil, err
+	}
+
+	return x.spawn(fn, arguments, expr.Call.Paren), nil
 }
 
+// errNilChain unwinds an optional chain from a '?.' on nil to the
+// OptionalChainExpr around it.
+var errNilChain = errors.New("nil in optional chain")
+
 func (x *Interpreter) VisitGetExpr(expr *Get) (any, error) {
 	object, err := x.evaluate(expr.Object)
 	if err != nil {
 		return nil, err
 	}
 
-	if instance, ok := object.(*InstanceImpl); ok {
-		return instance.Get(expr.Name)
+	if expr.Optional && object == nil {
+		return nil, errNilChain
+	}
+
+	return x.property(object, expr.Name)
+}
+
+func (x *Interpreter) VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error) {
+	value, err := x.evaluate(expr.Chain)
+	if errors.Is(err, errNilChain) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+// property returns the property of object called name.
+func (x *Interpreter) property(object any, name Token) (any, error) {
+	switch object := object.(type) {
+	case *InstanceImpl:
+		return object.Get(name)
+	case *ListImpl:
+		return object.Get(name)
+	case *MapImpl:
+		return object.Get(name)
+	case *GeneratorImpl:
+		return object.Get(name)
+	case *TaskImpl:
+		return object.Get(name)
+	case *ChannelImpl:
+		return object.Get(name)
+	case *moduleImpl:
+		return object.Get(name)
+	case string:
+		return stringMethod(object, name)
 	}
 
-	return nil, RuntimeError{"only instances have properties", expr.Name}
+	return nil, RuntimeError{"only instances have properties", name}
 }
 
 func (x *Interpreter) VisitSetExpr(expr *Set) (any, error) {
@@ -247,16 +535,42 @@ func (x *Interpreter) VisitSetExpr(expr *Set) (any, error) {
 		instance = v
 	}
 
+	var current any
+	if expr.Operator.Type != Equal {
+		if current, err = instance.Get(expr.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	value, err := x.evaluate(expr.Value)
 	if err != nil {
 		return nil, err
 	}
 
-	instance.Set(expr.Name, value)
+	if expr.Operator.Type != Equal {
+		if value, err = x.compound(expr.Operator, current, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = x.setProperty(instance, expr.Name, value); err != nil {
+		return nil, err
+	}
 
 	return value, nil
 }
 
+// setProperty sets the field of instance called name.
+func (x *Interpreter) setProperty(instance *InstanceImpl, name Token, value any) error {
+	if _, ok := instance.field(name.Lexeme); !ok {
+		if err := x.allocate(variableSize); err != nil {
+			return err
+		}
+	}
+
+	return instance.Set(name, value)
+}
+
 func (x *Interpreter) VisitThisExpr(expr *ThisExpr) (any, error) {
 	return x.lookupVariable(expr.Keyword, expr)
 }
@@ -265,7 +579,12 @@ func (x *Interpreter) VisitSuperExpr(expr *SuperExpr) (any, error) {
 	distance := x.locals[expr]
 
 	super, _ := x.environment.GetAt(distance, "super")
-	superclass := super.(*ClassImpl)
+
+	// Trait methods see a nil 'super' when mixed into a class without one.
+	superclass, ok := super.(*ClassImpl)
+	if !ok {
+		return nil, RuntimeError{"can't use 'super' in a class with no superclass", expr.Keyword}
+	}
 
 	obj, _ := x.environment.GetAt(distance-1, "this")
 	object := obj.(*InstanceImpl)
@@ -294,12 +613,24 @@ func (x *Interpreter) VisitPrintStmt(stmt *PrintStmt) error {
 		return err
 	}
 
-	fmt.Println(x.stringify(value))
+	line, err := x.stringify(value)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	x.console.output.Lock()
+	defer x.console.output.Unlock()
+
+	_, err = fmt.Fprintln(x.Stdout, line)
+
+	return err
 }
 
 func (x *Interpreter) VisitVarStmt(stmt *VarStmt) error {
+	if err := x.checkRedeclaration(stmt.Name); err != nil {
+		return err
+	}
+
 	var value any
 	var err error
 
@@ -310,13 +641,26 @@ func (x *Interpreter) VisitVarStmt(stmt *VarStmt) error {
 		}
 	}
 
-	x.environment.Define(stmt.Name.Lexeme, value)
+	if err = x.allocate(variableSize); err != nil {
+		return err
+	}
+
+	if stmt.Constant {
+		x.environment.DefineConstant(stmt.Name.Lexeme, value)
+	} else {
+		x.environment.Define(stmt.Name.Lexeme, value)
+	}
 
 	return nil
 }
 
 func (x *Interpreter) VisitBlockStmt(stmt *BlockStmt) error {
-	return x.executeBlock(stmt.Statements, &Environment{enclosing: x.environment})
+	environment, err := x.newEnvironment(x.environment)
+	if err != nil {
+		return err
+	}
+
+	return x.executeBlock(stmt.Statements, environment)
 }
 
 func (x *Interpreter) VisitIfStmt(stmt *IfStmt) error {
@@ -357,7 +701,99 @@ func (x *Interpreter) VisitWhileStmt(stmt *WhileStmt) error {
 	return nil
 }
 
+func (x *Interpreter) VisitForStmt(stmt *ForStmt) error {
+	previous := x.environment
+
+	defer func() {
+		x.environment = previous
+	}()
+
+	environment, err := x.newEnvironment(x.environment)
+	if err != nil {
+		return err
+	}
+
+	x.environment = environment
+
+	if stmt.Initializer != nil {
+		err := x.execute(stmt.Initializer)
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		if stmt.Condition != nil {
+			condition, err := x.evaluate(stmt.Condition)
+			if err != nil {
+				return err
+			}
+
+			if !x.isTruthy(condition) {
+				break
+			}
+		}
+
+		err := x.execute(stmt.Body)
+		if err != nil {
+			return err
+		}
+
+		if stmt.Increment != nil {
+			_, err = x.evaluate(stmt.Increment)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (x *Interpreter) VisitForInStmt(stmt *ForInStmt) error {
+	iterable, err := x.evaluate(stmt.Iterable)
+	if err != nil {
+		return err
+	}
+
+	next, err := x.iterate(iterable, stmt.Keyword)
+	if err != nil {
+		return err
+	}
+
+	for {
+		value, ok, err := next()
+		if err != nil || !ok {
+			return err
+		}
+
+		// Each iteration has a variable of its own, for closures to capture.
+		environment, err := x.newEnvironment(x.environment)
+		if err != nil {
+			return err
+		}
+
+		if err = x.allocate(variableSize); err != nil {
+			return err
+		}
+
+		environment.Define(stmt.Name.Lexeme, value)
+
+		if err = x.executeBlock([]Stmt{stmt.Body}, environment); err != nil {
+			return err
+		}
+	}
+}
+
 func (x *Interpreter) VisitFunctionStmt(stmt *FunctionStmt) error {
+	if err := x.checkRedeclaration(stmt.Name); err != nil {
+		return err
+	}
+
+	if err := x.allocate(functionSize + variableSize); err != nil {
+		return err
+	}
+
 	fn := &FunctionImpl{stmt, x.environment, false}
 
 	x.environment.Define(stmt.Name.Lexeme, fn)
@@ -379,6 +815,20 @@ func (x *Interpreter) VisitReturnStmt(stmt *ReturnStmt) error {
 	panic(FunctionReturn{value})
 }
 
+func (x *Interpreter) VisitYieldStmt(stmt *YieldStmt) error {
+	var value any
+	var err error
+
+	if stmt.Value != nil {
+		value, err = x.evaluate(stmt.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return x.generator.yield(value)
+}
+
 func (x *Interpreter) VisitClassStmt(stmt *ClassStmt) error {
 	var superclassImpl *ClassImpl
 
@@ -394,6 +844,10 @@ func (x *Interpreter) VisitClassStmt(stmt *ClassStmt) error {
 		}
 	}
 
+	if err := x.checkRedeclaration(stmt.Name); err != nil {
+		return err
+	}
+
 	x.environment.Define(stmt.Name.Lexeme, nil)
 
 	if stmt.Superclass != nil {
@@ -414,13 +868,18 @@ func (x *Interpreter) VisitClassStmt(stmt *ClassStmt) error {
 		methods[method.Name.Lexeme] = function
 	}
 
-	klass := &ClassImpl{name: stmt.Name.Lexeme, methods: methods, superclass: superclassImpl}
-
 	if superclassImpl != nil {
 		x.environment = x.environment.enclosing
 	}
 
-	err := x.environment.Assign(stmt.Name, klass)
+	traits, err := x.mixTraits(stmt, methods, superclassImpl)
+	if err != nil {
+		return err
+	}
+
+	klass := &ClassImpl{name: stmt.Name.Lexeme, methods: methods, superclass: superclassImpl, traits: traits}
+
+	err = x.environment.Assign(stmt.Name, klass)
 	if err != nil {
 		return err
 	}
@@ -431,6 +890,18 @@ func (x *Interpreter) VisitClassStmt(stmt *ClassStmt) error {
 // endregion
 
 // region private helpers
+
+// checkRedeclaration fails if name declares again a global constant, which
+// only interactive interpreters allow. Local constants can't be redeclared
+// either, as the Resolver rejects any local declared twice in a scope.
+func (x *Interpreter) checkRedeclaration(name Token) error {
+	if x.environment == x.globals && !x.Interactive && x.globals.isConstant(name.Lexeme) {
+		return RuntimeError{"can't redeclare constant '" + name.Lexeme + "'", name}
+	}
+
+	return nil
+}
+
 func (x *Interpreter) executeBlock(statements []Stmt, environment *Environment) error {
 	previous := x.environment
 
@@ -455,6 +926,16 @@ func (x *Interpreter) evaluate(expr Expr) (any, error) {
 }
 
 func (x *Interpreter) execute(stmt Stmt) error {
+	if err := x.step(); err != nil {
+		return err
+	}
+
+	if x.debugger != nil {
+		if err := x.debugger.beforeStatement(x, stmt); err != nil {
+			return err
+		}
+	}
+
 	return stmt.Accept(x)
 }
 
@@ -468,24 +949,26 @@ func (x *Interpreter) lookupVariable(name Token, expr Expr) (any, error) {
 	return x.globals.Get(name)
 }
 
-func (x *Interpreter) stringify(value any) string {
-	if value == nil {
-		return "nil"
-	}
-
-	if v, ok := value.(float64); ok {
-		return strconv.FormatFloat(v, 'f', -1, 64)
-	}
+// assignVariable assigns value to the variable expr refers to.
+func (x *Interpreter) assignVariable(name Token, expr Expr, value any) error {
+	if distance, ok := x.locals[expr]; ok {
+		x.environment.AssignAt(distance, name, value)
 
-	if v, ok := value.(fmt.Stringer); ok {
-		return v.String()
+		return nil
 	}
 
-	return fmt.Sprintf("%v", value)
+	return x.globals.Assign(name, value)
 }
 
-func (x *Interpreter) isEqual(a any, b any) bool {
-	return a == b
+// isEqual compares a and b, with the __eq method of a if it has one.
+func (x *Interpreter) isEqual(a any, b any) (bool, error) {
+	if method := x.operator(a, "__eq"); method != nil {
+		result, err := x.call(method, []any{b}, method.declaration.Name)
+
+		return x.isTruthy(result), err
+	}
+
+	return a == b, nil
 }
 
 func (x *Interpreter) isTruthy(value any) bool {
@@ -521,8 +1004,11 @@ func (x *Interpreter) checkNumberOperands(operator Token, left any, right any) e
 // endregion
 
 // region Errors
-func runtimeError(err RuntimeError) {
-	fmt.Printf("%s\n[line %d]\n", err.Message, err.Token.Line)
+func (x *Interpreter) runtimeError(err RuntimeError) {
+	x.console.output.Lock()
+	defer x.console.output.Unlock()
+
+	_, _ = fmt.Fprintf(x.Stderr, "%s\n[line %d]\n", err.Message, err.Token.Line)
 }
 
 type RuntimeError struct {
@@ -0,0 +1,102 @@
+package main
+
+// iterate returns the function a for-in loop calls for each value of
+// iterable, which reports false once there are no more. Lists, maps, strings,
+// generators and channels are iterated natively; instances through their
+// iterator() method, which returns an object with hasNext() and next().
+func (x *Interpreter) iterate(iterable any, keyword Token) (func() (any, bool, error), error) {
+	switch iterable := iterable.(type) {
+	case *ListImpl:
+		// Elements pushed while the loop runs are visited too.
+		i := 0
+
+		return func() (any, bool, error) {
+			value, ok := iterable.at(i)
+			i++
+
+			return value, ok, nil
+		}, nil
+	case *MapImpl:
+		keys, _ := iterable.entries()
+
+		return func() (any, bool, error) {
+			if len(keys) == 0 {
+				return nil, false, nil
+			}
+
+			key := keys[0]
+			keys = keys[1:]
+
+			return key, true, nil
+		}, nil
+	case string:
+		characters := []rune(iterable)
+
+		return func() (any, bool, error) {
+			if len(characters) == 0 {
+				return nil, false, nil
+			}
+
+			character := string(characters[0])
+			characters = characters[1:]
+
+			return character, true, x.allocateString(len(character))
+		}, nil
+	case *GeneratorImpl:
+		return func() (any, bool, error) {
+			value, ok, err := iterable.take(x, true)
+
+			return value, ok, reportAt(err, keyword)
+		}, nil
+	case *ChannelImpl:
+		return func() (any, bool, error) {
+			return iterable.receive(x)
+		}, nil
+	case *InstanceImpl:
+		if iterable.klass.FindMethod("iterator") != nil {
+			return x.iterateInstance(iterable, keyword)
+		}
+	}
+
+	return nil, RuntimeError{"can only iterate over lists, maps, strings, generators, channels and instances with an iterator method", keyword}
+}
+
+// iterateInstance follows the iterator protocol: the iterator() method of
+// instance returns an object whose hasNext() tells whether next() has
+// another value to return.
+func (x *Interpreter) iterateInstance(instance *InstanceImpl, keyword Token) (func() (any, bool, error), error) {
+	iterator, err := x.callMethod(instance, "iterator", keyword)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() (any, bool, error) {
+		hasNext, err := x.callMethod(iterator, "hasNext", keyword)
+		if err != nil || !x.isTruthy(hasNext) {
+			return nil, false, err
+		}
+
+		value, err := x.callMethod(iterator, "next", keyword)
+
+		return value, err == nil, err
+	}, nil
+}
+
+// callMethod calls the method of object called name with no arguments.
+func (x *Interpreter) callMethod(object any, name string, keyword Token) (any, error) {
+	method, err := x.property(object, Token{Identifier, name, nil, keyword.Line, keyword.Column})
+	if err != nil {
+		return nil, err
+	}
+
+	fn, ok := method.(Callable)
+	if !ok {
+		return nil, RuntimeError{"'" + name + "' must be a method", keyword}
+	}
+
+	if arity := fn.Arity(); arity > 0 {
+		return nil, RuntimeError{"'" + name + "' must take no arguments", keyword}
+	}
+
+	return x.call(fn, nil, keyword)
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+func newJsonModule() *moduleImpl {
+	module := &moduleImpl{name: "json", members: map[string]any{}}
+
+	module.define("parse", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		text, err := stringArgument("json.parse", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		// Decoded values take a few times the space of their text.
+		if err = interpreter.allocate(4 * len(text)); err != nil {
+			return nil, err
+		}
+
+		value, err := parseJson(text)
+		if err != nil {
+			return nil, nativeErrorf("json.parse: %s", err)
+		}
+
+		return value, nil
+	})
+
+	// json.stringify(value) is compact; json.stringify(value, indent) puts
+	// each member on its own line, indented by that many spaces.
+	module.define("stringify", -1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		if len(arguments) != 1 && len(arguments) != 2 {
+			return nil, nativeErrorf("'json.stringify' expects 1 or 2 arguments but got %d", len(arguments))
+		}
+
+		indent := 0
+		if len(arguments) == 2 && arguments[1] != nil {
+			var err error
+			if indent, err = integerArgument("json.stringify", arguments, 1); err != nil {
+				return nil, err
+			}
+		}
+
+		encoder := &jsonEncoder{interpreter: interpreter, visiting: make(map[any]bool)}
+		if err := encoder.encode(arguments[0]); err != nil {
+			return nil, err
+		}
+
+		if err := interpreter.allocateString(encoder.buffer.Len()); err != nil {
+			return nil, err
+		}
+
+		if indent <= 0 {
+			return encoder.buffer.String(), nil
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, encoder.buffer.Bytes(), "", strings.Repeat(" ", indent)); err != nil {
+			return nil, nativeErrorf("json.stringify: %s", err)
+		}
+
+		return indented.String(), nil
+	})
+
+	return module
+}
+
+// region decoding
+func parseJson(text string) (any, error) {
+	decoder := json.NewDecoder(strings.NewReader(text))
+
+	value, err := decodeJson(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after the top-level value")
+	}
+
+	return value, nil
+}
+
+// decodeJson reads a value token by token, so objects keep their key order.
+func decodeJson(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected end of input")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '[':
+			list := &ListImpl{}
+
+			for decoder.More() {
+				element, err := decodeJson(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				list.elements = append(list.elements, element)
+			}
+
+			if _, err = decoder.Token(); err != nil {
+				return nil, err
+			}
+
+			return list, nil
+		case '{':
+			object := newMap()
+
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := decodeJson(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				object.set(key.(string), value)
+			}
+
+			if _, err = decoder.Token(); err != nil {
+				return nil, err
+			}
+
+			return object, nil
+		}
+	case float64, string, bool, nil:
+		return token, nil
+	}
+
+	return nil, errors.New("unexpected token")
+}
+
+// endregion
+
+// region encoding
+type jsonEncoder struct {
+	interpreter *Interpreter
+	buffer      bytes.Buffer
+	visiting    map[any]bool // containers being encoded, to detect cycles
+}
+
+func (x *jsonEncoder) encode(value any) error {
+	switch value := value.(type) {
+	case nil:
+		x.buffer.WriteString("null")
+	case bool, string:
+		x.literal(value)
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nativeErrorf("json.stringify: can't encode %s", x.interpreter.inspect(value))
+		}
+
+		x.literal(value)
+	case *ListImpl:
+		elements := value.snapshot()
+
+		return x.container(value, '[', ']', len(elements), func(i int) error {
+			return x.encode(elements[i])
+		})
+	case *MapImpl:
+		keys, values := value.entries()
+
+		return x.container(value, '{', '}', len(keys), func(i int) error {
+			return x.member(keys[i], values[i])
+		})
+	case *InstanceImpl:
+		fields := value.snapshot()
+
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		return x.container(value, '{', '}', len(names), func(i int) error {
+			return x.member(names[i], fields[names[i]])
+		})
+	default:
+		return nativeErrorf("json.stringify: can't encode %s", x.interpreter.inspect(value))
+	}
+
+	return nil
+}
+
+func (x *jsonEncoder) container(value any, open, close byte, length int, element func(i int) error) error {
+	if x.visiting[value] {
+		return nativeErrorf("json.stringify: cycle detected")
+	}
+
+	x.visiting[value] = true
+	defer delete(x.visiting, value)
+
+	x.buffer.WriteByte(open)
+
+	for i := 0; i < length; i++ {
+		if i > 0 {
+			x.buffer.WriteByte(',')
+		}
+
+		if err := element(i); err != nil {
+			return err
+		}
+	}
+
+	x.buffer.WriteByte(close)
+
+	return nil
+}
+
+func (x *jsonEncoder) member(key string, value any) error {
+	x.literal(key)
+	x.buffer.WriteByte(':')
+
+	return x.encode(value)
+}
+
+// literal writes a string, number or boolean the way encoding/json does,
+// minus its escaping of HTML characters.
+func (x *jsonEncoder) literal(value any) {
+	encoder := json.NewEncoder(&x.buffer)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+
+	x.buffer.Truncate(x.buffer.Len() - 1) // Encode ends with a newline
+}
+
+// endregion
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseJson(t *testing.T) {
+	interpreter := (&Interpreter{}).Init()
+
+	tests := map[string]string{
+		`{"z": 1, "a": [true, null, "s"]}`: `{z: 1, a: [true, nil, s]}`,
+		` 3.25 `:                           `3.25`,
+		`"café"`:                           `café`,
+		`[]`:                               `[]`,
+	}
+
+	for text, expected := range tests {
+		value, err := parseJson(text)
+		if err != nil {
+			t.Errorf("parsing %s: %v", text, err)
+			continue
+		}
+
+		if got := interpreter.inspect(value); got != expected {
+			t.Errorf("parsing %s: expected %s, got %s", text, expected, got)
+		}
+	}
+}
+
+func TestParseJsonErrors(t *testing.T) {
+	for _, text := range []string{``, `{`, `[1,]`, `{"a" 1}`, `1 2`, `nul`} {
+		if _, err := parseJson(text); err == nil {
+			t.Errorf("expected parsing %q to fail", text)
+		}
+	}
+}
+
+func TestJsonStringifyErrorsAreRuntimeErrors(t *testing.T) {
+	_, err := runWithPolicy(t, nil, `json.stringify(0/0);`)
+
+	var runtimeErr RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "json.stringify: can't encode NaN" {
+		t.Errorf("expected a runtime error for NaN, got %v", err)
+	}
+}
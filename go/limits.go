@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limits bound the work a single Interpret call may do. The zero value puts
+// no bound on steps, time or memory, and the default one on call depth.
+type Limits struct {
+	// MaxSteps is the most statements that may be executed.
+	MaxSteps int
+	// MaxCallDepth is how deeply calls may nest; 0 means
+	// defaultMaxCallDepth, which keeps runaway recursion from overflowing
+	// the Go stack.
+	MaxCallDepth int
+	// Timeout is how long the program may run.
+	Timeout time.Duration
+	// MaxMemory is roughly how many bytes the program may allocate.
+	MaxMemory int64
+}
+
+const defaultMaxCallDepth = 10000
+
+var (
+	ErrStepLimit      = errors.New("step limit exceeded")
+	ErrCallDepthLimit = errors.New("call depth limit exceeded")
+	ErrTimeout        = errors.New("timeout exceeded")
+)
+
+// budget is what a program has used up of its Limits. The tasks of a program
+// share it.
+type budget struct {
+	steps       atomic.Int64
+	allocated   atomic.Int64
+	interrupt   atomic.Pointer[interruption]
+	interrupted chan struct{} // closed once interrupt is set
+	tasks       sync.WaitGroup
+
+	generatorMutex sync.Mutex // guards generators
+	generators     map[*generatorState]struct{}
+
+	// blockMutex guards the fields below, and the channels and tasks of the
+	// program, so a task is counted as blocked exactly while it waits.
+	blockMutex sync.Mutex
+	running    int // the main program and its unfinished tasks
+	blocked    int // how many of them wait on a channel or task
+}
+
+// interruption is why a running program has to stop early, set from the
+// watcher goroutine or by a failing task.
+type interruption struct {
+	err error
+}
+
+// stop interrupts the program with err, unless it has already been.
+func (x *budget) stop(err error) {
+	if x.interrupt.CompareAndSwap(nil, &interruption{err}) {
+		close(x.interrupted)
+	}
+}
+
+func (x *budget) addGenerator(generator *generatorState) {
+	x.generatorMutex.Lock()
+	defer x.generatorMutex.Unlock()
+
+	if x.generators == nil {
+		x.generators = make(map[*generatorState]struct{})
+	}
+
+	x.generators[generator] = struct{}{}
+}
+
+func (x *budget) removeGenerator(generator *generatorState) {
+	x.generatorMutex.Lock()
+	defer x.generatorMutex.Unlock()
+
+	delete(x.generators, generator)
+}
+
+// closeGenerators closes the generators started and not finished.
+func (x *budget) closeGenerators() {
+	x.generatorMutex.Lock()
+	defer x.generatorMutex.Unlock()
+
+	for generator := range x.generators {
+		generator.close()
+	}
+
+	x.generators = nil
+}
+
+// interruption returns why the program has been interrupted, or nil.
+func (x *budget) interruption() error {
+	if interruption := x.interrupt.Load(); interruption != nil {
+		return interruption.err
+	}
+
+	return nil
+}
+
+// watch stops the program, at its next statement or call, once ctx is done
+// or the timeout expires. The returned function ends the watch.
+func (x *Interpreter) watch(ctx context.Context) func() {
+	x.budget.interrupt.Store(nil)
+	x.budget.interrupted = make(chan struct{})
+
+	if ctx.Done() == nil && x.Limits.Timeout <= 0 {
+		return func() {}
+	}
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+	if x.Limits.Timeout > 0 {
+		timer = time.NewTimer(x.Limits.Timeout)
+		timeout = timer.C
+	}
+
+	done := make(chan struct{})
+	interrupted := x.budget.interrupted
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			x.budget.stop(ctx.Err())
+		case <-timeout:
+			x.budget.stop(ErrTimeout)
+		case <-done:
+		case <-interrupted:
+		}
+	}()
+
+	return func() {
+		close(done)
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// step accounts for one more statement and reports why the program must
+// stop, if it must.
+func (x *Interpreter) step() error {
+	if err := x.budget.interruption(); err != nil {
+		return err
+	}
+
+	steps := x.budget.steps.Add(1)
+	if x.Limits.MaxSteps > 0 && steps > int64(x.Limits.MaxSteps) {
+		return ErrStepLimit
+	}
+
+	return nil
+}
+
+func (x *Interpreter) enterCall() error {
+	if err := x.budget.interruption(); err != nil {
+		return err
+	}
+
+	limit := x.Limits.MaxCallDepth
+	if limit <= 0 {
+		limit = defaultMaxCallDepth
+	}
+
+	if x.depth >= limit {
+		return ErrCallDepthLimit
+	}
+
+	x.depth++
+
+	return nil
+}
+
+func (x *Interpreter) exitCall() {
+	x.depth--
+}
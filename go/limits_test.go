@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func interpretWithLimits(ctx context.Context, limits Limits, source string) error {
+	interpreter := (&Interpreter{Stdout: io.Discard, Stderr: io.Discard, Limits: limits}).Init()
+
+	statements, err := parse(source)
+	if err != nil {
+		return err
+	}
+
+	if err = interpreter.Resolve(statements); err != nil {
+		return err
+	}
+
+	return interpreter.InterpretContext(ctx, statements)
+}
+
+func TestStepLimit(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{MaxSteps: 1000}, `while (true) {}`)
+	if !errors.Is(err, ErrStepLimit) {
+		t.Errorf("expected %v, got %v", ErrStepLimit, err)
+	}
+
+	err = interpretWithLimits(context.Background(), Limits{MaxSteps: 1000}, `for (var i = 0; i < 10; i = i + 1) {}`)
+	if err != nil {
+		t.Errorf("expected a short loop to fit the budget, got %v", err)
+	}
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	source := `fun f(n) { return f(n + 1); } f(0);`
+
+	err := interpretWithLimits(context.Background(), Limits{MaxCallDepth: 50}, source)
+	if !errors.Is(err, ErrCallDepthLimit) {
+		t.Errorf("expected %v, got %v", ErrCallDepthLimit, err)
+	}
+
+	// Without a configured limit the default one stops the recursion before
+	// the Go stack overflows.
+	err = interpretWithLimits(context.Background(), Limits{}, source)
+	if !errors.Is(err, ErrCallDepthLimit) {
+		t.Errorf("expected %v, got %v", ErrCallDepthLimit, err)
+	}
+
+	err = interpretWithLimits(context.Background(), Limits{MaxCallDepth: 50}, `
+		fun count(n) { if (n > 0) count(n - 1); }
+		for (var i = 0; i < 100; i = i + 1) count(40);
+	`)
+	if err != nil {
+		t.Errorf("expected calls that return to free their depth, got %v", err)
+	}
+
+	// Generator bodies run on goroutines of their own, nested in the calls
+	// that resume them.
+	err = interpretWithLimits(context.Background(), Limits{MaxCallDepth: 50}, `
+		var n = 0;
+		fun g() {
+		  n = n + 1;
+		  if (n < 2000) yield g().next();
+		  else yield n;
+		}
+		g().next();
+	`)
+	if !errors.Is(err, ErrCallDepthLimit) {
+		t.Errorf("expected %v from recursing generators, got %v", ErrCallDepthLimit, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	start := time.Now()
+
+	err := interpretWithLimits(context.Background(), Limits{Timeout: 20 * time.Millisecond}, `while (true) {}`)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %v, got %v", ErrTimeout, err)
+	}
+
+	err = interpretWithLimits(context.Background(), Limits{Timeout: 20 * time.Millisecond}, `time.sleep(10);`)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %v while sleeping, got %v", ErrTimeout, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the timeouts to stop the programs promptly, took %v", elapsed)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	err := interpretWithLimits(ctx, Limits{}, `fun spin() { while (true) {} } spin();`)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import "sync"
+
+// ListImpl is the list value returned by natives such as split, and built by
+// the list native. It is safe for concurrent use.
+type ListImpl struct {
+	mutex    sync.RWMutex
+	elements []any
+}
+
+func newList(elements []any) *ListImpl {
+	return &ListImpl{elements: elements}
+}
+
+// Get returns the method of x called name, bound to x.
+func (x *ListImpl) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "len":
+		return &nativeFunction{"len", 0, func(_ *Interpreter, _ []any) (any, error) {
+			return float64(x.len()), nil
+		}}, nil
+	case "get":
+		return &nativeFunction{"get", 1, func(_ *Interpreter, arguments []any) (any, error) {
+			x.mutex.RLock()
+			defer x.mutex.RUnlock()
+
+			i, err := x.index("get", arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			return x.elements[i], nil
+		}}, nil
+	case "set":
+		return &nativeFunction{"set", 2, func(_ *Interpreter, arguments []any) (any, error) {
+			x.mutex.Lock()
+			defer x.mutex.Unlock()
+
+			i, err := x.index("set", arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			x.elements[i] = arguments[1]
+
+			return arguments[1], nil
+		}}, nil
+	case "push":
+		return &nativeFunction{"push", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			if err := interpreter.allocate(valueSize); err != nil {
+				return nil, err
+			}
+
+			x.push(arguments[0])
+
+			return nil, nil
+		}}, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+// index checks the index argument of method; x must be locked.
+func (x *ListImpl) index(method string, arguments []any) (int, error) {
+	i, err := integerArgument(method, arguments, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if i < 0 || i >= len(x.elements) {
+		return 0, nativeErrorf("list index %d out of range for length %d", i, len(x.elements))
+	}
+
+	return i, nil
+}
+
+// at returns the element at i, if there is one.
+func (x *ListImpl) at(i int) (any, bool) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	if i >= len(x.elements) {
+		return nil, false
+	}
+
+	return x.elements[i], true
+}
+
+func (x *ListImpl) len() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	return len(x.elements)
+}
+
+func (x *ListImpl) push(value any) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	x.elements = append(x.elements, value)
+}
+
+// snapshot returns a copy of the elements of x.
+func (x *ListImpl) snapshot() []any {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	return append([]any{}, x.elements...)
+}
+
+func funList(interpreter *Interpreter, arguments []any) (any, error) {
+	if err := interpreter.allocateList(len(arguments)); err != nil {
+		return nil, err
+	}
+
+	return newList(append([]any{}, arguments...)), nil
+}
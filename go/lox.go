@@ -1,95 +1,111 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 )
 
-var interpreter = (&Interpreter{}).Init()
-
 func main() {
-	/* AST Printer */
-	//expression := &Binary{
-	//	Left: &Unary{
-	//		Operator: Token{Minus, "-", nil, 1},
-	//		Right:    &Literal{123},
-	//	},
-	//	Operator: Token{Star, "*", nil, 1},
-	//	Right:    &Grouping{&Literal{45.67}},
-	//}
-	//
-	//fmt.Println(astPrinter{}.Print(expression))
-
-	/* Original code */
-	if len(os.Args) > 2 {
-		fmt.Println("Usage: glox [script]")
-		os.Exit(64)
-	} else if len(os.Args) == 2 {
-		err := runFile(os.Args[1])
-		if err != nil {
-			fmt.Println(err)
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	if len(os.Args) > 1 {
+		interpreter := (&Interpreter{Policy: fullAccessPolicy(), Args: os.Args[2:]}).Init()
+
+		err := runFile(interpreter, os.Args[1])
+
+		// Runtime errors have already been reported by the interpreter.
+		var runtimeErr RuntimeError
+		var exitErr *ExitError
+		if errors.As(err, &runtimeErr) {
+			os.Exit(70)
+		}
+		var compileErr *CompileError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		if errors.As(err, &compileErr) {
+			fmt.Fprint(os.Stderr, err)
 			os.Exit(65)
 		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(70)
+		}
 	} else {
-		runPrompt()
+		runPrompt((&Interpreter{Policy: fullAccessPolicy(), Interactive: true}).Init())
 	}
 }
 
-func runFile(fileName string) error {
+func runFile(interpreter *Interpreter, fileName string) error {
 	bytes, err := os.ReadFile(fileName)
 	panicIfError(err)
 
-	return run(string(bytes))
+	return runSource(interpreter, string(bytes))
 }
 
-func runPrompt() {
-	stdin := bufio.NewScanner(os.Stdin)
-
+// runPrompt reads lines through the interpreter, so scripts calling the
+// input natives read the lines typed after them.
+func runPrompt(interpreter *Interpreter) {
 	for {
 		fmt.Print("> ")
 
-		if !stdin.Scan() {
+		line, ok, err := interpreter.readLine()
+		panicIfError(err)
+
+		if !ok {
 			break
 		}
 
-		panicIfError(stdin.Err())
+		err = runSource(interpreter, line)
 
-		err := run(stdin.Text())
-		if err != nil {
-			fmt.Printf("%v\n", err)
+		var runtimeErr RuntimeError
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+		if err != nil && !errors.As(err, &runtimeErr) {
+			fmt.Fprintln(os.Stderr, strings.TrimSpace(err.Error()))
 		}
 	}
 }
 
-func run(source string) error {
-	scanner := NewScanner(source)
-
-	tokens, err := scanner.ScanTokens()
+// runSource parses, resolves and runs source on interpreter.
+func runSource(interpreter *Interpreter, source string) error {
+	statements, err := parse(source)
 	if err != nil {
 		return err
 	}
 
-	parser := NewParser(tokens)
-
-	statements, err := parser.Parse()
+	err = interpreter.Resolve(statements)
 	if err != nil {
 		return err
 	}
 
-	resolver := NewResolver(interpreter)
-
-	err = resolver.Resolve(statements)
+	err = interpreter.Interpret(statements)
 	if err != nil {
 		return err
 	}
 
-	err = interpreter.Interpret(statements)
+	return nil
+}
+
+func parse(source string) ([]Stmt, error) {
+	scanner := NewScanner(source)
+
+	tokens, err := scanner.ScanTokens()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	parser := NewParser(tokens)
+
+	return parser.Parse()
 }
 
 func panicIfError(err error) {
@@ -0,0 +1,730 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// lspServer is a Language Server Protocol server for Lox speaking JSON-RPC
+// over a pair of streams. Documents are fully re-analysed on every change by
+// the same scanner, parser and resolver the interpreter uses.
+type lspServer struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*lspDocument
+	builtins  []lspCompletionItem // the native globals, the same for every document
+	shutdown  bool
+	exitCode  int
+}
+
+func newLspServer(reader io.Reader, writer io.Writer) *lspServer {
+	var builtins []lspCompletionItem
+
+	for name, value := range (&Interpreter{}).Init().globals.snapshot() {
+		kind := lspCompletionVariable
+		if _, ok := value.(Callable); ok {
+			kind = lspCompletionFunction
+		}
+
+		builtins = append(builtins, lspCompletionItem{Label: name, Kind: kind})
+	}
+
+	return &lspServer{
+		reader:    bufio.NewReader(reader),
+		writer:    writer,
+		documents: make(map[string]*lspDocument),
+		builtins:  builtins,
+		exitCode:  1,
+	}
+}
+
+// serve handles messages until the client sends 'exit' or closes the input.
+func (x *lspServer) serve() error {
+	for {
+		body, err := readMessage(x.reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var request rpcRequest
+		if err = json.Unmarshal(body, &request); err != nil {
+			err = x.respond(nil, nil, &rpcError{rpcParseError, err.Error()})
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if request.Method == "exit" {
+			if x.shutdown {
+				x.exitCode = 0
+			}
+
+			return nil
+		}
+
+		result, rErr := x.handle(request)
+
+		if request.ID == nil {
+			continue
+		}
+
+		if err = x.respond(request.ID, result, rErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (x *lspServer) handle(request rpcRequest) (any, *rpcError) {
+	var err error
+
+	switch request.Method {
+	case "initialize":
+		return x.initialize(), nil
+	case "initialized", "$/cancelRequest", "$/setTrace":
+		return nil, nil
+	case "shutdown":
+		x.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err = json.Unmarshal(request.Params, &params); err == nil {
+			err = x.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err = json.Unmarshal(request.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			changes := params.ContentChanges
+			err = x.update(params.TextDocument.URI, changes[len(changes)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err = json.Unmarshal(request.Params, &params); err == nil {
+			delete(x.documents, params.TextDocument.URI)
+			err = x.publishDiagnostics(params.TextDocument.URI, []lspDiagnostic{})
+		}
+	case "textDocument/definition":
+		return x.withPosition(request.Params, x.definition)
+	case "textDocument/references":
+		return x.withPosition(request.Params, x.references)
+	case "textDocument/hover":
+		return x.withPosition(request.Params, x.hover)
+	case "textDocument/completion":
+		return x.withPosition(request.Params, x.completion)
+	case "textDocument/documentSymbol":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err = json.Unmarshal(request.Params, &params); err != nil {
+			break
+		}
+
+		document, ok := x.documents[params.TextDocument.URI]
+		if !ok {
+			return []lspDocumentSymbol{}, nil
+		}
+
+		return document.symbols(document.analysis.statements), nil
+	default:
+		if request.ID != nil {
+			return nil, &rpcError{rpcMethodNotFound, "method not found: " + request.Method}
+		}
+	}
+
+	if err != nil {
+		return nil, &rpcError{rpcInvalidParams, err.Error()}
+	}
+
+	return nil, nil
+}
+
+func (x *lspServer) initialize() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":       1, // full document on every change
+			"definitionProvider":     true,
+			"referencesProvider":     true,
+			"hoverProvider":          true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]any{},
+		},
+		"serverInfo": map[string]any{"name": "glox"},
+	}
+}
+
+func (x *lspServer) update(uri string, text string) error {
+	document := &lspDocument{uri: uri, lines: strings.Split(text, "\n")}
+	document.analysis = analyze(text)
+	x.documents[uri] = document
+
+	diagnostics := []lspDiagnostic{}
+	if document.analysis.err != nil {
+		diagnostics = append(diagnostics, document.diagnostic(document.analysis.err))
+	}
+
+	return x.publishDiagnostics(uri, diagnostics)
+}
+
+func (x *lspServer) publishDiagnostics(uri string, diagnostics []lspDiagnostic) error {
+	return writeMessage(x.writer, rpcNotification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params: map[string]any{
+			"uri":         uri,
+			"diagnostics": diagnostics,
+		},
+	})
+}
+
+func (x *lspServer) respond(id json.RawMessage, result any, rErr *rpcError) error {
+	response := rpcResponse{JSONRPC: "2.0", ID: id, Error: rErr}
+	if id == nil {
+		response.ID = json.RawMessage("null")
+	}
+
+	if rErr == nil {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		response.Result = encoded
+	}
+
+	return writeMessage(x.writer, response)
+}
+
+// withPosition decodes TextDocumentPositionParams and calls handler with the
+// document and the token-space position they refer to.
+func (x *lspServer) withPosition(params json.RawMessage, handler func(*lspDocument, int, int, json.RawMessage) any) (any, *rpcError) {
+	var position struct {
+		TextDocument lspTextDocument `json:"textDocument"`
+		Position     lspPosition     `json:"position"`
+	}
+
+	if err := json.Unmarshal(params, &position); err != nil {
+		return nil, &rpcError{rpcInvalidParams, err.Error()}
+	}
+
+	document, ok := x.documents[position.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+
+	line, column := document.tokenPosition(position.Position)
+
+	return handler(document, line, column, params), nil
+}
+
+func (x *lspServer) definition(document *lspDocument, line int, column int, _ json.RawMessage) any {
+	symbol := document.analysis.symbolAt(line, column)
+	if symbol == nil {
+		return nil
+	}
+
+	return document.location(symbol.name)
+}
+
+func (x *lspServer) references(document *lspDocument, line int, column int, params json.RawMessage) any {
+	var context struct {
+		Context struct {
+			IncludeDeclaration bool `json:"includeDeclaration"`
+		} `json:"context"`
+	}
+	_ = json.Unmarshal(params, &context)
+
+	locations := []lspLocation{}
+
+	symbol := document.analysis.symbolAt(line, column)
+	if symbol == nil {
+		return locations
+	}
+
+	if context.Context.IncludeDeclaration {
+		locations = append(locations, document.location(symbol.name))
+	}
+
+	for _, use := range symbol.uses {
+		locations = append(locations, document.location(use))
+	}
+
+	return locations
+}
+
+func (x *lspServer) hover(document *lspDocument, line int, column int, _ json.RawMessage) any {
+	symbol := document.analysis.symbolAt(line, column)
+	if symbol == nil {
+		return nil
+	}
+
+	return map[string]any{
+		"contents": map[string]any{
+			"kind":  "markdown",
+			"value": "```lox\n" + symbol.signature() + "\n```",
+		},
+	}
+}
+
+func (x *lspServer) completion(document *lspDocument, _ int, _ int, _ json.RawMessage) any {
+	seen := make(map[string]bool)
+	items := []lspCompletionItem{}
+
+	add := func(label string, kind int) {
+		if !seen[label] {
+			seen[label] = true
+			items = append(items, lspCompletionItem{Label: label, Kind: kind})
+		}
+	}
+
+	for keyword := range keywords {
+		add(keyword, lspCompletionKeyword)
+	}
+
+	for _, symbol := range document.analysis.symbols {
+		add(symbol.name.Lexeme, symbol.completionKind())
+	}
+
+	for _, builtin := range x.builtins {
+		add(builtin.Label, builtin.Kind)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	return items
+}
+
+// region analysis
+type lspAnalysis struct {
+	statements []Stmt
+	spans      map[Stmt]Span
+	err        error
+	symbols    []*lspSymbol
+	names      []lspName             // every declaring or using name, with its symbol
+	globals    map[string]*lspSymbol // first declaration of each global
+	byName     map[[2]int]*lspSymbol // declarations by line and column
+	pending    []Token               // uses of globals, bound once all declarations are known
+}
+
+type lspName struct {
+	token  Token
+	symbol *lspSymbol
+}
+
+type lspSymbol struct {
+	name Token
+	node Stmt // *VarStmt, *ClassStmt, *TraitStmt, *ForInStmt, the *FunctionStmt declaring a function or parameter, or nil for a pattern variable
+	uses []Token
+}
+
+func analyze(source string) *lspAnalysis {
+	analysis := &lspAnalysis{
+		globals: make(map[string]*lspSymbol),
+		byName:  make(map[[2]int]*lspSymbol),
+	}
+
+	tokens, err := NewScanner(source).ScanTokens()
+	if err != nil {
+		analysis.err = err
+		return analysis
+	}
+
+	parser := NewParser(tokens)
+
+	analysis.statements, analysis.err = parser.Parse()
+	analysis.spans = parser.Spans()
+
+	if analysis.err != nil {
+		return analysis
+	}
+
+	resolver := NewResolver()
+	resolver.listen(analysis)
+
+	_, analysis.err = resolver.Resolve(analysis.statements)
+
+	for _, use := range analysis.pending {
+		if symbol, ok := analysis.globals[use.Lexeme]; ok {
+			symbol.uses = append(symbol.uses, use)
+			analysis.names = append(analysis.names, lspName{use, symbol})
+		}
+	}
+
+	return analysis
+}
+
+func (x *lspAnalysis) declared(name Token, node Stmt, local bool) {
+	symbol := &lspSymbol{name: name, node: node}
+
+	x.symbols = append(x.symbols, symbol)
+	x.names = append(x.names, lspName{name, symbol})
+	x.byName[[2]int{name.Line, name.Column}] = symbol
+
+	if _, ok := x.globals[name.Lexeme]; !ok && !local {
+		x.globals[name.Lexeme] = symbol
+	}
+}
+
+func (x *lspAnalysis) referenced(name Token, declaration Token, local bool) {
+	if !local {
+		x.pending = append(x.pending, name)
+		return
+	}
+
+	if symbol, ok := x.byName[[2]int{declaration.Line, declaration.Column}]; ok {
+		symbol.uses = append(symbol.uses, name)
+		x.names = append(x.names, lspName{name, symbol})
+	}
+}
+
+func (x *lspAnalysis) assigned(Token, Token, bool) {}
+
+// symbolAt returns the symbol declared or used by the name at line and
+// column, both 1-based like token positions.
+func (x *lspAnalysis) symbolAt(line int, column int) *lspSymbol {
+	for _, name := range x.names {
+		if name.token.Line == line && column >= name.token.Column && column <= name.token.Column+len(name.token.Lexeme) {
+			return name.symbol
+		}
+	}
+
+	return nil
+}
+
+func (x *lspSymbol) signature() string {
+	switch node := x.node.(type) {
+	case *VarStmt:
+		if node.Constant {
+			return "const " + x.name.Lexeme + annotation(node.Type)
+		}
+
+		return "var " + x.name.Lexeme + annotation(node.Type)
+	case *ClassStmt:
+		if node.Superclass != nil {
+			return "class " + x.name.Lexeme + " < " + node.Superclass.Name.Lexeme
+		}
+
+		return "class " + x.name.Lexeme
+	case *TraitStmt:
+		return "trait " + x.name.Lexeme
+	case *FunctionStmt:
+		if node.Name != x.name {
+			for i, param := range node.Params {
+				if param == x.name {
+					return x.name.Lexeme + annotation(node.ParamTypes[i]) + " (parameter of " + node.Name.Lexeme + ")"
+				}
+			}
+
+			return x.name.Lexeme + " (parameter of " + node.Name.Lexeme + ")"
+		}
+
+		return "fun " + functionSignature(node)
+	}
+
+	return x.name.Lexeme
+}
+
+func (x *lspSymbol) completionKind() int {
+	switch node := x.node.(type) {
+	case *ClassStmt, *TraitStmt:
+		return lspCompletionClass
+	case *FunctionStmt:
+		if node.Name == x.name {
+			return lspCompletionFunction
+		}
+	}
+
+	return lspCompletionVariable
+}
+
+func functionSignature(fn *FunctionStmt) string {
+	params := make([]string, 0, len(fn.Params))
+	for i, param := range fn.Params {
+		params = append(params, param.Lexeme+annotation(fn.ParamTypes[i]))
+	}
+
+	return fn.Name.Lexeme + "(" + strings.Join(params, ", ") + ")" + annotation(fn.ReturnType)
+}
+
+// endregion
+
+// region documents
+type lspDocument struct {
+	uri      string
+	lines    []string
+	analysis *lspAnalysis
+}
+
+// tokenPosition converts an LSP position (0-based, UTF-16 code units) into a
+// 1-based line and byte column as used by tokens.
+func (x *lspDocument) tokenPosition(position lspPosition) (int, int) {
+	if position.Line < 0 || position.Line >= len(x.lines) {
+		return position.Line + 1, position.Character + 1
+	}
+
+	text := x.lines[position.Line]
+
+	units := 0
+	for offset, r := range text {
+		if units >= position.Character {
+			return position.Line + 1, offset + 1
+		}
+
+		units += utf16Len(r)
+	}
+
+	return position.Line + 1, len(text) + 1
+}
+
+// lspPosition converts a 1-based line and byte column into an LSP position.
+func (x *lspDocument) lspPosition(line int, column int) lspPosition {
+	if line < 1 || line > len(x.lines) {
+		return lspPosition{Line: line - 1, Character: column - 1}
+	}
+
+	text := x.lines[line-1]
+	if column-1 > len(text) {
+		column = len(text) + 1
+	}
+
+	units := 0
+	for _, r := range text[:column-1] {
+		units += utf16Len(r)
+	}
+
+	return lspPosition{Line: line - 1, Character: units}
+}
+
+func (x *lspDocument) tokenRange(token Token) lspRange {
+	return lspRange{
+		Start: x.lspPosition(token.Line, token.Column),
+		End:   x.lspPosition(token.Line, token.Column+len(token.Lexeme)),
+	}
+}
+
+func (x *lspDocument) lineRange(first int, last int) lspRange {
+	end := x.lspPosition(last, 1)
+	if last >= 1 && last <= len(x.lines) {
+		end = x.lspPosition(last, len(x.lines[last-1])+1)
+	}
+
+	return lspRange{Start: x.lspPosition(first, 1), End: end}
+}
+
+func (x *lspDocument) location(token Token) lspLocation {
+	return lspLocation{URI: x.uri, Range: x.tokenRange(token)}
+}
+
+func (x *lspDocument) diagnostic(err error) lspDiagnostic {
+	diagnostic := lspDiagnostic{
+		Severity: lspSeverityError,
+		Source:   "glox",
+		Message:  strings.TrimSpace(err.Error()),
+	}
+
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		diagnostic.Range = x.lineRange(1, 1)
+		return diagnostic
+	}
+
+	diagnostic.Message = compileErr.Message
+
+	if compileErr.Token != nil && compileErr.Token.Type != EOF {
+		diagnostic.Range = x.tokenRange(*compileErr.Token)
+	} else {
+		diagnostic.Range = x.lineRange(compileErr.Line, compileErr.Line)
+	}
+
+	return diagnostic
+}
+
+// symbols lists the classes, methods and functions declared in statements,
+// nested the way they are in the source.
+func (x *lspDocument) symbols(statements []Stmt) []lspDocumentSymbol {
+	symbols := []lspDocumentSymbol{}
+
+	for _, stmt := range statements {
+		switch node := stmt.(type) {
+		case *ClassStmt:
+			symbol := x.symbol(node, node.Name, lspSymbolClass, "")
+			symbol.Children = x.methodSymbols(node.Methods)
+			symbols = append(symbols, symbol)
+		case *TraitStmt:
+			symbol := x.symbol(node, node.Name, lspSymbolInterface, "")
+			symbol.Children = x.methodSymbols(node.Methods)
+			symbols = append(symbols, symbol)
+		case *FunctionStmt:
+			symbol := x.symbol(node, node.Name, lspSymbolFunction, functionSignature(node))
+			symbol.Children = x.symbols(node.Body)
+			symbols = append(symbols, symbol)
+		case *BlockStmt:
+			symbols = append(symbols, x.symbols(node.Statements)...)
+		case *IfStmt:
+			symbols = append(symbols, x.symbols([]Stmt{node.ThenBranch})...)
+			if node.ElseBranch != nil {
+				symbols = append(symbols, x.symbols([]Stmt{node.ElseBranch})...)
+			}
+		case *WhileStmt:
+			symbols = append(symbols, x.symbols([]Stmt{node.Body})...)
+		case *ForStmt:
+			symbols = append(symbols, x.symbols([]Stmt{node.Body})...)
+		case *ForInStmt:
+			symbols = append(symbols, x.symbols([]Stmt{node.Body})...)
+		}
+	}
+
+	return symbols
+}
+
+// methodSymbols lists the methods of a class or trait body.
+func (x *lspDocument) methodSymbols(methods []*FunctionStmt) []lspDocumentSymbol {
+	symbols := []lspDocumentSymbol{}
+
+	for _, method := range methods {
+		kind := lspSymbolMethod
+		if method.Name.Lexeme == "init" {
+			kind = lspSymbolConstructor
+		}
+
+		symbol := x.symbol(method, method.Name, kind, functionSignature(method))
+		symbol.Children = x.symbols(method.Body)
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+func (x *lspDocument) symbol(stmt Stmt, name Token, kind int, detail string) lspDocumentSymbol {
+	span := x.analysis.spans[stmt]
+
+	return lspDocumentSymbol{
+		Name:           name.Lexeme,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          x.lineRange(span.Start, span.End),
+		SelectionRange: x.tokenRange(name),
+		Children:       []lspDocumentSymbol{},
+	}
+}
+
+// utf16Len is the number of UTF-16 code units needed to encode r, the unit
+// LSP positions are counted in.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+
+	return 1
+}
+
+// endregion
+
+// region protocol types
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+const (
+	lspSeverityError = 1
+
+	lspSymbolClass       = 5
+	lspSymbolMethod      = 6
+	lspSymbolConstructor = 9
+	lspSymbolInterface   = 11
+	lspSymbolFunction    = 12
+
+	lspCompletionFunction = 3
+	lspCompletionVariable = 6
+	lspCompletionClass    = 7
+	lspCompletionKeyword  = 14
+)
+
+type rpcRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspTextDocument struct {
+	URI string `json:"uri"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail,omitempty"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children"`
+}
+
+type lspCompletionItem struct {
+	Label string `json:"label"`
+	Kind  int    `json:"kind"`
+}
+
+// endregion
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// frames encodes messages as the client side of a Content-Length framed
+// session.
+func frames(t *testing.T, messages ...any) *bytes.Buffer {
+	t.Helper()
+
+	var input bytes.Buffer
+	for _, message := range messages {
+		if err := writeMessage(&input, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &input
+}
+
+// readFrames decodes every message a server wrote to output.
+func readFrames(t *testing.T, output *bytes.Buffer) []map[string]json.RawMessage {
+	t.Helper()
+
+	var messages []map[string]json.RawMessage
+
+	reader := bufio.NewReader(output)
+	for {
+		body, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var message map[string]json.RawMessage
+		if err = json.Unmarshal(body, &message); err != nil {
+			t.Fatal(err)
+		}
+
+		messages = append(messages, message)
+	}
+}
+
+// lspSession runs the server over requests and returns the results of the
+// requests by id and the diagnostics it published, in order.
+func lspSession(t *testing.T, requests ...map[string]any) (map[string]json.RawMessage, [][]lspDiagnostic) {
+	t.Helper()
+
+	messages := make([]any, 0, len(requests))
+	for _, message := range requests {
+		messages = append(messages, message)
+	}
+
+	var output bytes.Buffer
+
+	server := newLspServer(frames(t, messages...), &output)
+	if err := server.serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]json.RawMessage)
+	var diagnostics [][]lspDiagnostic
+
+	for _, message := range readFrames(t, &output) {
+		if id, ok := message["id"]; ok {
+			if rErr, ok := message["error"]; ok {
+				t.Errorf("request %s failed: %s", id, rErr)
+			}
+
+			results[string(id)] = message["result"]
+			continue
+		}
+
+		var params struct {
+			Diagnostics []lspDiagnostic `json:"diagnostics"`
+		}
+		if err := json.Unmarshal(message["params"], &params); err != nil {
+			t.Fatal(err)
+		}
+
+		diagnostics = append(diagnostics, params.Diagnostics)
+	}
+
+	return results, diagnostics
+}
+
+func request(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notification(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func decode(t *testing.T, raw json.RawMessage, value any) {
+	t.Helper()
+
+	if err := json.Unmarshal(raw, value); err != nil {
+		t.Fatalf("can't decode %s: %v", raw, err)
+	}
+}
+
+func TestLspSession(t *testing.T) {
+	const uri = "file:///greet.lox"
+	const source = `fun greet(name) {
+  return "hi " + name;
+}
+
+class Greeter {
+  init(name) {
+    this.name = name;
+  }
+}
+
+print greet("ada");
+`
+
+	document := map[string]any{"uri": uri}
+	at := func(line int, character int) map[string]any {
+		return map[string]any{"textDocument": document, "position": map[string]any{"line": line, "character": character}}
+	}
+
+	references := at(10, 7)
+	references["context"] = map[string]any{"includeDeclaration": true}
+
+	results, diagnostics := lspSession(t,
+		request(1, "initialize", map[string]any{}),
+		notification("initialized", map[string]any{}),
+		notification("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "lox", "version": 1, "text": source},
+		}),
+		request(2, "textDocument/definition", at(10, 7)),
+		request(3, "textDocument/references", references),
+		request(4, "textDocument/hover", at(10, 7)),
+		request(5, "textDocument/documentSymbol", map[string]any{"textDocument": document}),
+		request(6, "textDocument/completion", at(10, 0)),
+		notification("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []map[string]any{{"text": "print greet(;\n"}},
+		}),
+		request(7, "shutdown", nil),
+		notification("exit", nil),
+	)
+
+	var capabilities struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	decode(t, results["1"], &capabilities)
+
+	if capabilities.Capabilities["definitionProvider"] != true {
+		t.Errorf("expected definitions to be offered, got %v", capabilities.Capabilities)
+	}
+
+	declaration := lspRange{Start: lspPosition{0, 4}, End: lspPosition{0, 9}}
+	use := lspRange{Start: lspPosition{10, 6}, End: lspPosition{10, 11}}
+
+	var definition lspLocation
+	decode(t, results["2"], &definition)
+
+	if definition != (lspLocation{uri, declaration}) {
+		t.Errorf("expected the definition at %v, got %v", declaration, definition)
+	}
+
+	var locations []lspLocation
+	decode(t, results["3"], &locations)
+
+	if !reflect.DeepEqual(locations, []lspLocation{{uri, declaration}, {uri, use}}) {
+		t.Errorf("expected the declaration and the use, got %v", locations)
+	}
+
+	var hover struct {
+		Contents struct {
+			Value string `json:"value"`
+		} `json:"contents"`
+	}
+	decode(t, results["4"], &hover)
+
+	if hover.Contents.Value != "```lox\nfun greet(name)\n```" {
+		t.Errorf("unexpected hover %q", hover.Contents.Value)
+	}
+
+	var symbols []lspDocumentSymbol
+	decode(t, results["5"], &symbols)
+
+	if len(symbols) != 2 || symbols[0].Name != "greet" || symbols[1].Name != "Greeter" ||
+		len(symbols[1].Children) != 1 || symbols[1].Children[0].Kind != lspSymbolConstructor {
+		t.Errorf("expected greet and Greeter with its initializer, got %+v", symbols)
+	}
+
+	var items []lspCompletionItem
+	decode(t, results["6"], &items)
+
+	kinds := make(map[string]int)
+	for _, item := range items {
+		kinds[item.Label] = item.Kind
+	}
+
+	for label, kind := range map[string]int{"greet": lspCompletionFunction, "Greeter": lspCompletionClass, "clock": lspCompletionFunction, "while": lspCompletionKeyword} {
+		if kinds[label] != kind {
+			t.Errorf("expected %q to complete as kind %d, got %d", label, kind, kinds[label])
+		}
+	}
+
+	if len(diagnostics) != 2 || len(diagnostics[0]) != 0 || len(diagnostics[1]) != 1 {
+		t.Fatalf("expected no diagnostics then one, got %v", diagnostics)
+	}
+
+	if got := diagnostics[1][0]; got.Message != "expect expression" || got.Range.Start != (lspPosition{0, 12}) {
+		t.Errorf("unexpected diagnostic %+v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import "sync"
+
+// MapImpl is a map from strings to Lox values that remembers the order keys
+// were first set in, as JSON objects are written. It is safe for concurrent
+// use.
+type MapImpl struct {
+	mutex  sync.RWMutex
+	keys   []string
+	values map[string]any
+}
+
+func newMap() *MapImpl {
+	return &MapImpl{values: make(map[string]any)}
+}
+
+func (x *MapImpl) get(key string) (any, bool) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	value, ok := x.values[key]
+
+	return value, ok
+}
+
+func (x *MapImpl) set(key string, value any) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if _, ok := x.values[key]; !ok {
+		x.keys = append(x.keys, key)
+	}
+
+	x.values[key] = value
+}
+
+func (x *MapImpl) remove(key string) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	if _, ok := x.values[key]; !ok {
+		return
+	}
+
+	delete(x.values, key)
+
+	for i, k := range x.keys {
+		if k == key {
+			x.keys = append(x.keys[:i], x.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Get returns the method of x called name, bound to x.
+func (x *MapImpl) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "len":
+		return &nativeFunction{"len", 0, func(_ *Interpreter, _ []any) (any, error) {
+			x.mutex.RLock()
+			defer x.mutex.RUnlock()
+
+			return float64(len(x.keys)), nil
+		}}, nil
+	case "get":
+		return &nativeFunction{"get", 1, func(_ *Interpreter, arguments []any) (any, error) {
+			key, err := stringArgument("get", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			value, _ := x.get(key)
+
+			return value, nil
+		}}, nil
+	case "set":
+		return &nativeFunction{"set", 2, func(interpreter *Interpreter, arguments []any) (any, error) {
+			key, err := stringArgument("set", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := x.get(key); !ok {
+				if err = interpreter.allocate(variableSize + len(key)); err != nil {
+					return nil, err
+				}
+			}
+
+			x.set(key, arguments[1])
+
+			return arguments[1], nil
+		}}, nil
+	case "has":
+		return &nativeFunction{"has", 1, func(_ *Interpreter, arguments []any) (any, error) {
+			key, err := stringArgument("has", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			_, ok := x.get(key)
+
+			return ok, nil
+		}}, nil
+	case "remove":
+		return &nativeFunction{"remove", 1, func(_ *Interpreter, arguments []any) (any, error) {
+			key, err := stringArgument("remove", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			x.remove(key)
+
+			return nil, nil
+		}}, nil
+	case "keys":
+		return &nativeFunction{"keys", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			names, _ := x.entries()
+
+			if err := interpreter.allocateList(len(names)); err != nil {
+				return nil, err
+			}
+
+			keys := make([]any, 0, len(names))
+			for _, key := range names {
+				keys = append(keys, key)
+			}
+
+			return newList(keys), nil
+		}}, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+// entries returns copies of the keys of x, in order, and of their values.
+func (x *MapImpl) entries() ([]string, []any) {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	values := make([]any, 0, len(x.keys))
+	for _, key := range x.keys {
+		values = append(values, x.values[key])
+	}
+
+	return append([]string{}, x.keys...), values
+}
+
+func funMap(interpreter *Interpreter, _ []any) (any, error) {
+	if err := interpreter.allocate(mapSize); err != nil {
+		return nil, err
+	}
+
+	return newMap(), nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// patternTypes maps the built-in type names patterns and 'is' can test for
+// to the names type() gives those types. Any other type name in a pattern
+// must be a class or trait.
+var patternTypes = map[string]string{
+	"Any":      "",
+	"Number":   "number",
+	"String":   "string",
+	"Bool":     "boolean",
+	"Nil":      "nil",
+	"List":     "list",
+	"Map":      "map",
+	"Function": "function",
+}
+
+func (x *Interpreter) VisitMatchExpr(expr *MatchExpr) (any, error) {
+	value, err := x.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.Arms {
+		for _, pattern := range arm.Patterns {
+			bindings := make(map[string]any)
+
+			ok, err := x.matchPattern(pattern, value, bindings)
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			// Alternatives bind nothing, so they'd all give the guard the
+			// same answer.
+			result, taken, err := x.takeArm(arm, bindings)
+			if err != nil || taken {
+				return result, err
+			}
+
+			break
+		}
+	}
+
+	return nil, RuntimeError{"no case matches " + x.inspect(value), expr.Keyword}
+}
+
+// takeArm evaluates the guard and, if it holds, the body of arm with the
+// pattern's bindings in scope.
+func (x *Interpreter) takeArm(arm *MatchArm, bindings map[string]any) (result any, taken bool, err error) {
+	environment, err := x.newEnvironment(x.environment)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for name, value := range bindings {
+		environment.Define(name, value)
+	}
+
+	previous := x.environment
+	x.environment = environment
+
+	defer func() {
+		x.environment = previous
+	}()
+
+	if arm.Guard != nil {
+		guard, err := x.evaluate(arm.Guard)
+		if err != nil || !x.isTruthy(guard) {
+			return nil, false, err
+		}
+	}
+
+	result, err = x.evaluate(arm.Body)
+
+	return result, true, err
+}
+
+// matchPattern reports whether value matches pattern, adding the variables
+// the pattern binds to bindings.
+func (x *Interpreter) matchPattern(pattern Pattern, value any, bindings map[string]any) (bool, error) {
+	switch pattern := pattern.(type) {
+	case *LiteralPattern:
+		return x.isEqual(value, pattern.Value)
+	case *BindingPattern:
+		if pattern.Type != nil {
+			ok, err := x.hasType(value, pattern.Type)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+
+		if pattern.Name.Lexeme != "_" {
+			bindings[pattern.Name.Lexeme] = value
+		}
+
+		return true, nil
+	case *ClassPattern:
+		class, err := x.evaluate(pattern.Class)
+		if err != nil {
+			return false, err
+		}
+
+		klass, ok := class.(*ClassImpl)
+		if !ok {
+			return false, RuntimeError{"'" + pattern.Class.Name.Lexeme + "' is not a class", pattern.Class.Name}
+		}
+
+		instance, ok := value.(*InstanceImpl)
+		if !ok || !instance.klass.isSubclassOf(klass) {
+			return false, nil
+		}
+
+		if len(pattern.Subpatterns) == 0 {
+			return true, nil
+		}
+
+		var params []Token
+		if initializer := klass.FindMethod("init"); initializer != nil {
+			params = initializer.declaration.Params
+		}
+
+		if len(pattern.Subpatterns) != len(params) {
+			return false, RuntimeError{
+				fmt.Sprintf("expected %d subpatterns for '%s' but got %d", len(params), klass.name, len(pattern.Subpatterns)),
+				pattern.Paren,
+			}
+		}
+
+		for i, subpattern := range pattern.Subpatterns {
+			field, ok := instance.field(params[i].Lexeme)
+			if !ok {
+				return false, nil
+			}
+
+			if ok, err = x.matchPattern(subpattern, field, bindings); err != nil || !ok {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// hasType reports whether value has the built-in type, or is an instance of
+// the class or trait, that name refers to.
+func (x *Interpreter) hasType(value any, name *Variable) (bool, error) {
+	if builtin, ok := patternTypes[name.Name.Lexeme]; ok {
+		return builtin == "" || builtin == typeName(value), nil
+	}
+
+	t, err := x.evaluate(name)
+	if err != nil {
+		return false, err
+	}
+
+	instance, isInstance := value.(*InstanceImpl)
+
+	switch t := t.(type) {
+	case *ClassImpl:
+		return isInstance && instance.klass.isSubclassOf(t), nil
+	case *TraitImpl:
+		return isInstance && instance.klass.hasTrait(t), nil
+	}
+
+	return false, RuntimeError{"'" + name.Name.Lexeme + "' is not a type", name.Name}
+}
+
+// patternBindings returns the names pattern binds, in order.
+func patternBindings(pattern Pattern) []Token {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		if pattern.Name.Lexeme != "_" {
+			return []Token{pattern.Name}
+		}
+	case *ClassPattern:
+		var names []Token
+		for _, subpattern := range pattern.Subpatterns {
+			names = append(names, patternBindings(subpattern)...)
+		}
+
+		return names
+	}
+
+	return nil
+}
+
+// patternVariables returns the class and type names pattern looks up.
+func patternVariables(pattern Pattern) []*Variable {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		if pattern.Type != nil {
+			if _, ok := patternTypes[pattern.Type.Name.Lexeme]; !ok {
+				return []*Variable{pattern.Type}
+			}
+		}
+	case *ClassPattern:
+		variables := []*Variable{pattern.Class}
+		for _, subpattern := range pattern.Subpatterns {
+			variables = append(variables, patternVariables(subpattern)...)
+		}
+
+		return variables
+	}
+
+	return nil
+}
+
+// patternString returns pattern as it's written in source.
+func patternString(pattern Pattern) string {
+	switch pattern := pattern.(type) {
+	case *LiteralPattern:
+		return (&formatter{}).expr(&Literal{pattern.Value})
+	case *BindingPattern:
+		if pattern.Type != nil {
+			return pattern.Name.Lexeme + ": " + pattern.Type.Name.Lexeme
+		}
+
+		return pattern.Name.Lexeme
+	case *ClassPattern:
+		subpatterns := make([]string, 0, len(pattern.Subpatterns))
+		for _, subpattern := range pattern.Subpatterns {
+			subpatterns = append(subpatterns, patternString(subpattern))
+		}
+
+		return pattern.Class.Name.Lexeme + "(" + strings.Join(subpatterns, ", ") + ")"
+	}
+
+	return ""
+}
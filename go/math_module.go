@@ -0,0 +1,120 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+func newMathModule() *moduleImpl {
+	module := &moduleImpl{name: "math", members: map[string]any{
+		"PI": math.Pi,
+		"E":  math.E,
+	}}
+
+	unary := map[string]func(float64) float64{
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"asin":  math.Asin,
+		"acos":  math.Acos,
+		"atan":  math.Atan,
+	}
+
+	for name, fn := range unary {
+		name, fn := name, fn
+
+		module.define(name, 1, func(_ *Interpreter, arguments []any) (any, error) {
+			n, err := numberArgument("math."+name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			return fn(n), nil
+		})
+	}
+
+	binary := map[string]func(float64, float64) float64{
+		"pow":   math.Pow,
+		"atan2": math.Atan2,
+	}
+
+	for name, fn := range binary {
+		name, fn := name, fn
+
+		module.define(name, 2, func(_ *Interpreter, arguments []any) (any, error) {
+			a, err := numberArgument("math."+name, arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			b, err := numberArgument("math."+name, arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			return fn(a, b), nil
+		})
+	}
+
+	module.define("min", -1, func(_ *Interpreter, arguments []any) (any, error) {
+		return extremum("math.min", arguments, math.Min)
+	})
+	module.define("max", -1, func(_ *Interpreter, arguments []any) (any, error) {
+		return extremum("math.max", arguments, math.Max)
+	})
+
+	// Each interpreter has its own generator, seeded from the clock until a
+	// script asks for a repeatable sequence. Its tasks share it.
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var mutex sync.Mutex
+
+	module.define("random", 0, func(_ *Interpreter, _ []any) (any, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return random.Float64(), nil
+	})
+	module.define("seed", 1, func(_ *Interpreter, arguments []any) (any, error) {
+		seed, err := integerArgument("math.seed", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		mutex.Lock()
+		random.Seed(int64(seed))
+		mutex.Unlock()
+
+		return nil, nil
+	})
+
+	return module
+}
+
+func extremum(name string, arguments []any, pick func(float64, float64) float64) (any, error) {
+	if len(arguments) == 0 {
+		return nil, nativeErrorf("'%s' expects at least one argument", name)
+	}
+
+	result, err := numberArgument(name, arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(arguments); i++ {
+		n, err := numberArgument(name, arguments, i)
+		if err != nil {
+			return nil, err
+		}
+
+		result = pick(result, n)
+	}
+
+	return result, nil
+}
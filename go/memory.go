@@ -0,0 +1,58 @@
+package main
+
+import "errors"
+
+// Approximate sizes, in bytes, of what Lox programs allocate. They only need
+// to keep a quota proportional to the memory a program really uses.
+const (
+	stringSize      = 16 // a string header, to which its bytes are added
+	valueSize       = 16 // an interface value in a list
+	variableSize    = 48 // a map entry for a variable, field or map key
+	environmentSize = 64
+	functionSize    = 64
+	instanceSize    = 48
+	listSize        = 32
+	mapSize         = 64
+	taskSize        = 64
+	channelSize     = 96
+)
+
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// allocate charges bytes to the program and fails once the program has
+// allocated more than Limits.MaxMemory. Memory is never given back: the
+// quota bounds what a program allocates in total, not what it holds at once.
+func (x *Interpreter) allocate(bytes int) error {
+	allocated := x.budget.allocated.Add(int64(bytes))
+
+	if x.Limits.MaxMemory > 0 && allocated > x.Limits.MaxMemory {
+		return ErrMemoryLimit
+	}
+
+	return nil
+}
+
+// fits reports whether the program could allocate bytes more, for natives to
+// check before building large values.
+func (x *Interpreter) fits(bytes int) bool {
+	return x.Limits.MaxMemory <= 0 || x.budget.allocated.Load()+int64(bytes) <= x.Limits.MaxMemory
+}
+
+// allocateString charges a string of length n.
+func (x *Interpreter) allocateString(n int) error {
+	return x.allocate(stringSize + n)
+}
+
+// allocateList charges a list of n elements, which are charged separately.
+func (x *Interpreter) allocateList(n int) error {
+	return x.allocate(listSize + n*valueSize)
+}
+
+// newEnvironment allocates the scope of a block or call.
+func (x *Interpreter) newEnvironment(enclosing *Environment) (*Environment, error) {
+	if err := x.allocate(environmentSize); err != nil {
+		return nil, err
+	}
+
+	return &Environment{enclosing: enclosing}, nil
+}
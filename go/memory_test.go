@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	programs := map[string]string{
+		"concatenation": `var s = "x"; while (true) s = s + s;`,
+		"fields":        `class P {} while (true) { var p = P(); p.a = 1; p.b = 2; }`,
+		"environments":  `fun f(n) { { var a = n; } return f; } while (true) f(1);`,
+		"instances":     `class A {} var a; while (true) a = A();`,
+		"repeat":        `"abc".repeat(1000000000);`,
+		"list":          `var l = list(); while (true) l.push(l);`,
+	}
+
+	for name, source := range programs {
+		err := interpretWithLimits(context.Background(), Limits{MaxMemory: 1 << 20}, source)
+		if !errors.Is(err, ErrMemoryLimit) {
+			t.Errorf("%s: expected %v, got %v", name, ErrMemoryLimit, err)
+		}
+	}
+}
+
+func TestMemoryLimitAllowsSmallPrograms(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{MaxMemory: 1 << 20}, `
+		class Point { init(x, y) { this.x = x; this.y = y; } }
+		var s = "";
+		for (var i = 0; i < 100; i = i + 1) {
+			var p = Point(i, i);
+			s = s + "ab";
+		}
+	`)
+	if err != nil {
+		t.Errorf("expected the program to fit in the quota, got %v", err)
+	}
+}
+
+func TestMemoryIsChargedPerInterpretCall(t *testing.T) {
+	interpreter := (&Interpreter{Limits: Limits{MaxMemory: 4096}}).Init()
+
+	statements, err := parse(`var s = "x".repeat(2000);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = interpreter.Interpret(statements); err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// moduleImpl is a namespace of natives, such as math, bound to a global.
+type moduleImpl struct {
+	name    string
+	members map[string]any
+}
+
+func (x *moduleImpl) Get(name Token) (any, error) {
+	if member, ok := x.members[name.Lexeme]; ok {
+		return member, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "' in module " + x.name, name}
+}
+
+func (x *moduleImpl) String() string {
+	return "<module " + x.name + ">"
+}
+
+// define adds a native function to x.
+func (x *moduleImpl) define(name string, arity int, fn func(interpreter *Interpreter, arguments []any) (any, error)) {
+	x.members[name] = &nativeFunction{x.name + "." + name, arity, fn}
+}
@@ -1,6 +1,11 @@
 package main
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
 
 type funClock struct{}
 
@@ -9,9 +14,106 @@ func (f *funClock) Arity() int {
 }
 
 func (f *funClock) Call(_ *Interpreter, _ []any) (any, error) {
-	return time.Now().Unix(), nil
+	return seconds(time.Now()), nil
 }
 
 func (f *funClock) String() string {
 	return "<native fn>"
 }
+
+// nativeFunction is a Callable implemented in Go. An arity of -1 accepts any
+// number of arguments.
+type nativeFunction struct {
+	name  string
+	arity int
+	fn    func(interpreter *Interpreter, arguments []any) (any, error)
+}
+
+func (f *nativeFunction) Arity() int {
+	return f.arity
+}
+
+func (f *nativeFunction) Call(interpreter *Interpreter, arguments []any) (any, error) {
+	return f.fn(interpreter, arguments)
+}
+
+func (f *nativeFunction) String() string {
+	return "<native fn>"
+}
+
+// nativeError is how natives report misuse. The interpreter turns it into a
+// RuntimeError at the call site, as natives don't know where they're called.
+type nativeError struct {
+	message string
+}
+
+func (x *nativeError) Error() string {
+	return x.message
+}
+
+func nativeErrorf(format string, args ...any) error {
+	return &nativeError{fmt.Sprintf(format, args...)}
+}
+
+// reportAt turns a nativeError into a RuntimeError at token, and returns
+// other errors as they are.
+func reportAt(err error, token Token) error {
+	var nErr *nativeError
+	if errors.As(err, &nErr) {
+		return RuntimeError{nErr.message, token}
+	}
+
+	return err
+}
+
+// region argument checks
+func stringArgument(name string, arguments []any, i int) (string, error) {
+	if value, ok := arguments[i].(string); ok {
+		return value, nil
+	}
+
+	return "", nativeErrorf("argument %d to '%s' must be a string", i+1, name)
+}
+
+func numberArgument(name string, arguments []any, i int) (float64, error) {
+	if value, ok := arguments[i].(float64); ok {
+		return value, nil
+	}
+
+	return 0, nativeErrorf("argument %d to '%s' must be a number", i+1, name)
+}
+
+func integerArgument(name string, arguments []any, i int) (int, error) {
+	value, ok := arguments[i].(float64)
+	if !ok || value != math.Trunc(value) || math.Abs(value) > math.MaxInt32 {
+		return 0, nativeErrorf("argument %d to '%s' must be an integer", i+1, name)
+	}
+
+	return int(value), nil
+}
+
+func instanceArgument(name string, arguments []any, i int) (*InstanceImpl, error) {
+	if value, ok := arguments[i].(*InstanceImpl); ok {
+		return value, nil
+	}
+
+	return nil, nativeErrorf("argument %d to '%s' must be an instance", i+1, name)
+}
+
+func classArgument(name string, arguments []any, i int) (*ClassImpl, error) {
+	if value, ok := arguments[i].(*ClassImpl); ok {
+		return value, nil
+	}
+
+	return nil, nativeErrorf("argument %d to '%s' must be a class", i+1, name)
+}
+
+func listArgument(name string, arguments []any, i int) (*ListImpl, error) {
+	if value, ok := arguments[i].(*ListImpl); ok {
+		return value, nil
+	}
+
+	return nil, nativeErrorf("argument %d to '%s' must be a list", i+1, name)
+}
+
+// endregion
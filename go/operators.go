@@ -0,0 +1,316 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Classes overload operators by defining methods with these names, which
+// must take the given number of parameters. __call takes any.
+var operatorArities = map[string]int{
+	"__add":      1,
+	"__sub":      1,
+	"__mul":      1,
+	"__div":      1,
+	"__mod":      1,
+	"__lt":       1,
+	"__le":       1,
+	"__gt":       1,
+	"__ge":       1,
+	"__eq":       1,
+	"__neg":      0,
+	"__index":    1,
+	"__setindex": 2,
+	"__str":      0,
+}
+
+// binaryOperators maps the binary operators that can be overloaded, apart
+// from equality, to their methods.
+var binaryOperators = map[TokenType]string{
+	Plus:         "__add",
+	Minus:        "__sub",
+	Star:         "__mul",
+	Slash:        "__div",
+	Percent:      "__mod",
+	Less:         "__lt",
+	LessEqual:    "__le",
+	Greater:      "__gt",
+	GreaterEqual: "__ge",
+}
+
+// compoundOperators maps the operators of compound assignments, increments
+// and decrements to the binary operators they apply.
+var compoundOperators = map[TokenType]TokenType{
+	PlusEqual:    Plus,
+	MinusEqual:   Minus,
+	StarEqual:    Star,
+	SlashEqual:   Slash,
+	PercentEqual: Percent,
+	PlusPlus:     Plus,
+	MinusMinus:   Minus,
+}
+
+func checkOperatorArity(method *FunctionStmt) error {
+	arity, ok := operatorArities[method.Name.Lexeme]
+	if !ok || len(method.Params) == arity {
+		return nil
+	}
+
+	parameters := "parameters"
+	if arity == 1 {
+		parameters = "parameter"
+	}
+
+	return TokenError(method.Name, fmt.Sprintf("'%s' must take %d %s", method.Name.Lexeme, arity, parameters))
+}
+
+// operator returns the method overloading an operator on value, bound to it,
+// if value is an instance whose class defines one.
+func (x *Interpreter) operator(value any, name string) *FunctionImpl {
+	if instance, ok := value.(*InstanceImpl); ok {
+		if method := instance.klass.FindMethod(name); method != nil {
+			return method.Bind(instance)
+		}
+	}
+
+	return nil
+}
+
+// compound applies the binary operator behind a compound assignment,
+// increment or decrement to the current value of its target and operand.
+func (x *Interpreter) compound(operator Token, current any, operand any) (any, error) {
+	operator.Type = compoundOperators[operator.Type]
+
+	return x.binary(operator, current, operand)
+}
+
+func (x *Interpreter) VisitUpdateExpr(expr *UpdateExpr) (any, error) {
+	get, set, err := x.reference(expr.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := get()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := x.compound(expr.Operator, old, 1.0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = set(value); err != nil {
+		return nil, err
+	}
+
+	if expr.Prefix {
+		return value, nil
+	}
+
+	return old, nil
+}
+
+// reference evaluates the object and index of target, a variable, property
+// or index, once, and returns functions getting and setting what it refers
+// to.
+func (x *Interpreter) reference(target Expr) (get func() (any, error), set func(any) error, err error) {
+	switch target := target.(type) {
+	case *Variable:
+		get = func() (any, error) {
+			return x.lookupVariable(target.Name, target)
+		}
+
+		set = func(value any) error {
+			return x.assignVariable(target.Name, target, value)
+		}
+	case *Get:
+		object, err := x.evaluate(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		instance, ok := object.(*InstanceImpl)
+		if !ok {
+			return nil, nil, RuntimeError{"only instances have fields", target.Name}
+		}
+
+		get = func() (any, error) {
+			return instance.Get(target.Name)
+		}
+
+		set = func(value any) error {
+			return x.setProperty(instance, target.Name, value)
+		}
+	case *IndexExpr:
+		object, err := x.evaluate(target.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		index, err := x.evaluate(target.Index)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		get = func() (any, error) {
+			return x.index(object, index, target.Bracket)
+		}
+
+		set = func(value any) error {
+			return x.setIndex(object, index, value, target.Bracket)
+		}
+	}
+
+	return get, set, nil
+}
+
+func (x *Interpreter) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	object, err := x.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := x.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return x.index(object, index, expr.Bracket)
+}
+
+// index returns the element of object at index.
+func (x *Interpreter) index(object any, index any, bracket Token) (any, error) {
+	switch object := object.(type) {
+	case *ListImpl:
+		object.mutex.RLock()
+		defer object.mutex.RUnlock()
+
+		i, err := x.checkIndex("list", index, len(object.elements), bracket)
+		if err != nil {
+			return nil, err
+		}
+
+		return object.elements[i], nil
+	case *MapImpl:
+		key, ok := index.(string)
+		if !ok {
+			return nil, RuntimeError{"map keys must be strings", bracket}
+		}
+
+		value, _ := object.get(key)
+
+		return value, nil
+	case string:
+		characters := []rune(object)
+
+		i, err := x.checkIndex("string", index, len(characters), bracket)
+		if err != nil {
+			return nil, err
+		}
+
+		character := string(characters[i])
+		if err = x.allocateString(len(character)); err != nil {
+			return nil, err
+		}
+
+		return character, nil
+	}
+
+	if method := x.operator(object, "__index"); method != nil {
+		return x.call(method, []any{index}, bracket)
+	}
+
+	return nil, RuntimeError{"can only index lists, maps, strings and instances with __index", bracket}
+}
+
+func (x *Interpreter) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	object, err := x.evaluate(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := x.evaluate(expr.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	var current any
+	if expr.Operator.Type != Equal {
+		if current, err = x.index(object, index, expr.Bracket); err != nil {
+			return nil, err
+		}
+	}
+
+	value, err := x.evaluate(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Operator.Type != Equal {
+		if value, err = x.compound(expr.Operator, current, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = x.setIndex(object, index, value, expr.Bracket); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// setIndex sets the element of object at index.
+func (x *Interpreter) setIndex(object any, index any, value any, bracket Token) error {
+	switch object := object.(type) {
+	case *ListImpl:
+		object.mutex.Lock()
+		defer object.mutex.Unlock()
+
+		i, err := x.checkIndex("list", index, len(object.elements), bracket)
+		if err != nil {
+			return err
+		}
+
+		object.elements[i] = value
+
+		return nil
+	case *MapImpl:
+		key, ok := index.(string)
+		if !ok {
+			return RuntimeError{"map keys must be strings", bracket}
+		}
+
+		if _, ok := object.get(key); !ok {
+			if err := x.allocate(variableSize + len(key)); err != nil {
+				return err
+			}
+		}
+
+		object.set(key, value)
+
+		return nil
+	}
+
+	if method := x.operator(object, "__setindex"); method != nil {
+		_, err := x.call(method, []any{index, value}, bracket)
+
+		return err
+	}
+
+	return RuntimeError{"can only assign to indexes of lists, maps and instances with __setindex", bracket}
+}
+
+// checkIndex checks index is an integer within a list or string of length.
+func (x *Interpreter) checkIndex(kind string, index any, length int, bracket Token) (int, error) {
+	i, ok := index.(float64)
+	if !ok || i != math.Trunc(i) {
+		return 0, RuntimeError{kind + " indexes must be integers", bracket}
+	}
+
+	if i < 0 || i >= float64(length) {
+		return 0, RuntimeError{fmt.Sprintf("%s index %s out of range for length %d", kind, x.inspect(i), length), bracket}
+	}
+
+	return int(i), nil
+}
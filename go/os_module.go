@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// ExitError is returned by Interpret when a script calls os.exit.
+type ExitError struct {
+	Code int
+}
+
+func (x *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", x.Code)
+}
+
+func newOsModule(args []string) *moduleImpl {
+	elements := make([]any, 0, len(args))
+	for _, arg := range args {
+		elements = append(elements, arg)
+	}
+
+	module := &moduleImpl{name: "os", members: map[string]any{
+		"args": newList(elements),
+	}}
+
+	module.define("getenv", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		name, err := stringArgument("os.getenv", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if !interpreter.Policy.allowsEnv() {
+			return nil, nativeErrorf("os.getenv: access to the environment denied")
+		}
+
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+
+		return nil, nil
+	})
+	module.define("exit", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		code, err := integerArgument("os.exit", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if !interpreter.Policy.allowsExit() {
+			return nil, nativeErrorf("os.exit: exiting denied")
+		}
+
+		return nil, &ExitError{code}
+	})
+
+	return module
+}
@@ -8,12 +8,27 @@ func NewParser(tokens []Token) *Parser {
 	return &Parser{
 		tokens:  tokens,
 		current: 0,
+		spans:   make(map[Stmt]Span),
 	}
 }
 
 type Parser struct {
-	tokens  []Token
-	current int
+	tokens    []Token
+	current   int
+	spans     map[Stmt]Span
+	generator bool // a yield was found in the function being parsed
+}
+
+// Span is the range of source lines a statement was parsed from.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Spans returns the source lines of every statement parsed so far, including
+// nested ones and class methods.
+func (x *Parser) Spans() map[Stmt]Span {
+	return x.spans
 }
 
 func (x *Parser) Parse() ([]Stmt, error) {
@@ -43,17 +58,24 @@ func (x *Parser) declaration() (Stmt, error) {
 	var stmt Stmt
 	var err error
 
+	start := x.peek()
+
 	if x.match(Class) {
 		stmt, err = x.classDeclaration()
+	} else if x.match(Trait) {
+		stmt, err = x.traitDeclaration()
 	} else if x.match(Fun) {
 		stmt, err = x.function("function")
 	} else if x.match(Var) {
 		stmt, err = x.varDeclaration()
+	} else if x.match(Const) {
+		stmt, err = x.constDeclaration()
 	} else {
 		stmt, err = x.statement()
 	}
 
-	if errors.Is(err, errParse) {
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
 		x.synchronize()
 
 		return nil, err
@@ -63,6 +85,8 @@ func (x *Parser) declaration() (Stmt, error) {
 		return nil, err
 	}
 
+	x.mark(stmt, start)
+
 	return stmt, nil
 }
 
@@ -83,7 +107,60 @@ func (x *Parser) classDeclaration() (*ClassStmt, error) {
 		superclass = &Variable{x.previous()}
 	}
 
-	_, err = x.consume(LeftBrace, "expect '{' before class body")
+	var traits []*Variable
+
+	if x.match(With) {
+		for {
+			_, err = x.consume(Identifier, "expect trait name")
+			if err != nil {
+				return nil, err
+			}
+
+			traits = append(traits, &Variable{x.previous()})
+
+			if !x.match(Comma) {
+				break
+			}
+		}
+	}
+
+	var fields []*FieldDecl
+
+	methods, err := x.methods("class", &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClassStmt{
+		Name:       name,
+		Fields:     fields,
+		Methods:    methods,
+		Superclass: superclass,
+		Traits:     traits,
+	}, nil
+}
+
+func (x *Parser) traitDeclaration() (*TraitStmt, error) {
+	name, err := x.consume(Identifier, "expect trait name")
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := x.methods("trait", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TraitStmt{
+		Name:    name,
+		Methods: methods,
+	}, nil
+}
+
+// methods parses the body of a class or trait, adding the field declarations
+// in it to fields unless that's nil.
+func (x *Parser) methods(kind string, fields *[]*FieldDecl) ([]*FunctionStmt, error) {
+	_, err := x.consume(LeftBrace, "expect '{' before "+kind+" body")
 	if err != nil {
 		return nil, err
 	}
@@ -91,23 +168,70 @@ func (x *Parser) classDeclaration() (*ClassStmt, error) {
 	var methods []*FunctionStmt
 	var method *FunctionStmt
 	for !x.check(RightBrace) && !x.isAtEnd() {
+		if fields != nil && x.check(Identifier) && x.checkNext(Colon) {
+			field, err := x.fieldDeclaration()
+			if err != nil {
+				return nil, err
+			}
+
+			*fields = append(*fields, field)
+			continue
+		}
+
 		method, err = x.function("method")
+		if err != nil {
+			return nil, err
+		}
+
 		methods = append(methods, method)
 	}
 
-	_, err = x.consume(RightBrace, "expect '}' after class body")
+	_, err = x.consume(RightBrace, "expect '}' after "+kind+" body")
 	if err != nil {
 		return nil, err
 	}
 
-	return &ClassStmt{
-		Name:       name,
-		Methods:    methods,
-		Superclass: superclass,
-	}, nil
+	return methods, nil
+}
+
+func (x *Parser) fieldDeclaration() (*FieldDecl, error) {
+	name := x.advance()
+	x.advance() // the ':'
+
+	fieldType, err := x.typeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(Semicolon, "expect ';' after field declaration")
+	if err != nil {
+		return nil, err
+	}
+
+	return &FieldDecl{Name: name, Type: fieldType}, nil
+}
+
+// optionalType parses the ': Type' that may follow a name.
+func (x *Parser) optionalType() (*TypeAnnotation, error) {
+	if !x.match(Colon) {
+		return nil, nil
+	}
+
+	return x.typeAnnotation()
+}
+
+func (x *Parser) typeAnnotation() (*TypeAnnotation, error) {
+	name, err := x.consume(Identifier, "expect type name")
+	if err != nil {
+		return nil, err
+	}
+
+	return &TypeAnnotation{Name: name}, nil
 }
 
 func (x *Parser) function(kind string) (*FunctionStmt, error) {
+	start := x.peek()
+
 	name, err := x.consume(Identifier, "expect "+kind+" name")
 	if err != nil {
 		return nil, err
@@ -119,6 +243,7 @@ func (x *Parser) function(kind string) (*FunctionStmt, error) {
 	}
 
 	var parameters []Token
+	var parameterTypes []*TypeAnnotation
 
 	if !x.check(RightParen) {
 		for {
@@ -131,7 +256,13 @@ func (x *Parser) function(kind string) (*FunctionStmt, error) {
 				return nil, err
 			}
 
+			paramType, err := x.optionalType()
+			if err != nil {
+				return nil, err
+			}
+
 			parameters = append(parameters, param)
+			parameterTypes = append(parameterTypes, paramType)
 
 			if !x.match(Comma) {
 				break
@@ -144,21 +275,38 @@ func (x *Parser) function(kind string) (*FunctionStmt, error) {
 		return nil, err
 	}
 
+	returnType, err := x.optionalType()
+	if err != nil {
+		return nil, err
+	}
+
 	_, err = x.consume(LeftBrace, "expect '{' before "+kind+" body")
 	if err != nil {
 		return nil, err
 	}
 
+	enclosingGenerator := x.generator
+	x.generator = false
+
 	statements, err := x.block()
 	if err != nil {
 		return nil, err
 	}
 
-	return &FunctionStmt{
-		Name:   name,
-		Params: parameters,
-		Body:   statements,
-	}, nil
+	function := &FunctionStmt{
+		Name:        name,
+		Params:      parameters,
+		ParamTypes:  parameterTypes,
+		ReturnType:  returnType,
+		Body:        statements,
+		IsGenerator: x.generator,
+	}
+
+	x.generator = enclosingGenerator
+
+	x.mark(function, start)
+
+	return function, nil
 }
 
 func (x *Parser) varDeclaration() (Stmt, error) {
@@ -167,6 +315,11 @@ func (x *Parser) varDeclaration() (Stmt, error) {
 		return nil, err
 	}
 
+	varType, err := x.optionalType()
+	if err != nil {
+		return nil, err
+	}
+
 	var initializer Expr
 
 	if x.match(Equal) {
@@ -181,10 +334,47 @@ func (x *Parser) varDeclaration() (Stmt, error) {
 		return nil, err
 	}
 
-	return &VarStmt{name, initializer}, nil
+	return &VarStmt{Name: name, Type: varType, Initializer: initializer}, nil
+}
+
+func (x *Parser) constDeclaration() (Stmt, error) {
+	name, err := x.consume(Identifier, "expect constant name")
+	if err != nil {
+		return nil, err
+	}
+
+	constType, err := x.optionalType()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(Equal, "expect '=' after constant name")
+	if err != nil {
+		return nil, err
+	}
+
+	initializer, err := x.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(Semicolon, "expect ';' after constant declaration")
+	if err != nil {
+		return nil, err
+	}
+
+	return &VarStmt{Name: name, Type: constType, Initializer: initializer, Constant: true}, nil
 }
 
-func (x *Parser) statement() (Stmt, error) {
+func (x *Parser) statement() (stmt Stmt, err error) {
+	start := x.peek()
+
+	defer func() {
+		if err == nil {
+			x.mark(stmt, start)
+		}
+	}()
+
 	if x.match(For) {
 		return x.forStatement()
 	}
@@ -201,6 +391,10 @@ func (x *Parser) statement() (Stmt, error) {
 		return x.returnStatement()
 	}
 
+	if x.match(Yield) {
+		return x.yieldStatement()
+	}
+
 	if x.match(While) {
 		return x.whileStatement()
 	}
@@ -241,12 +435,44 @@ func (x *Parser) returnStatement() (Stmt, error) {
 	}, nil
 }
 
+func (x *Parser) yieldStatement() (Stmt, error) {
+	keyword := x.previous()
+
+	var value Expr
+	var err error
+
+	if !x.check(Semicolon) {
+		value, err = x.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = x.consume(Semicolon, "expect ';' after yield value")
+	if err != nil {
+		return nil, err
+	}
+
+	x.generator = true
+
+	return &YieldStmt{
+		Keyword: keyword,
+		Value:   value,
+	}, nil
+}
+
 func (x *Parser) forStatement() (Stmt, error) {
+	keyword := x.previous()
+
 	_, err := x.consume(LeftParen, "expect '(' after 'for'")
 	if err != nil {
 		return nil, err
 	}
 
+	if x.isForIn() {
+		return x.forInStatement(keyword)
+	}
+
 	var initializer Stmt
 
 	if x.match(Semicolon) {
@@ -293,34 +519,60 @@ func (x *Parser) forStatement() (Stmt, error) {
 		return nil, err
 	}
 
-	if increment != nil {
-		body = &BlockStmt{
-			Statements: []Stmt{
-				body,
-				&ExpressionStmt{Expression: increment},
-			},
-		}
+	return &ForStmt{
+		Keyword:     keyword,
+		Initializer: initializer,
+		Condition:   condition,
+		Increment:   increment,
+		Body:        body,
+	}, nil
+}
+
+// isForIn reports whether the clauses of the for loop ahead are 'name in'
+// or 'var name in'.
+func (x *Parser) isForIn() bool {
+	i := x.current
+	if x.tokens[i].Type == Var {
+		i++
 	}
 
-	if condition == nil {
-		condition = &Literal{true}
+	return x.tokens[i].Type == Identifier && x.tokens[i+1].Type == In
+}
+
+func (x *Parser) forInStatement(keyword Token) (Stmt, error) {
+	x.match(Var)
+
+	name, err := x.consume(Identifier, "expect variable name")
+	if err != nil {
+		return nil, err
 	}
 
-	body = &WhileStmt{
-		Condition: condition,
-		Body:      body,
+	_, err = x.consume(In, "expect 'in' after variable name")
+	if err != nil {
+		return nil, err
 	}
 
-	if initializer != nil {
-		body = &BlockStmt{
-			Statements: []Stmt{
-				initializer,
-				body,
-			},
-		}
+	iterable, err := x.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(RightParen, "expect ')' after for-in clauses")
+	if err != nil {
+		return nil, err
 	}
 
-	return body, nil
+	body, err := x.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForInStmt{
+		Keyword:  keyword,
+		Name:     name,
+		Iterable: iterable,
+		Body:     body,
+	}, nil
 }
 
 func (x *Parser) ifStatement() (Stmt, error) {
@@ -440,13 +692,13 @@ func (x *Parser) expression() (Expr, error) {
 }
 
 func (x *Parser) assignment() (Expr, error) {
-	expr, err := x.or()
+	expr, err := x.conditional()
 	if err != nil {
 		return nil, err
 	}
 
-	if x.match(Equal) {
-		equals := x.previous()
+	if x.match(Equal, PlusEqual, MinusEqual, StarEqual, SlashEqual, PercentEqual) {
+		operator := x.previous()
 
 		value, err := x.assignment()
 		if err != nil {
@@ -455,18 +707,87 @@ func (x *Parser) assignment() (Expr, error) {
 
 		if v, ok := expr.(*Variable); ok {
 			return &Assign{
-				Name:  v.Name,
-				Value: value,
+				Name:     v.Name,
+				Operator: operator,
+				Value:    value,
 			}, nil
 		} else if get, ok := expr.(*Get); ok {
 			return &Set{
-				Object: get.Object,
-				Name:   get.Name,
-				Value:  value,
+				Object:   get.Object,
+				Name:     get.Name,
+				Operator: operator,
+				Value:    value,
+			}, nil
+		} else if index, ok := expr.(*IndexExpr); ok {
+			return &SetIndexExpr{
+				Object:   index.Object,
+				Bracket:  index.Bracket,
+				Index:    index.Index,
+				Operator: operator,
+				Value:    value,
 			}, nil
 		}
 
-		return nil, x.error(equals, "invalid assignment target")
+		return nil, x.error(operator, "invalid assignment target")
+	}
+
+	return expr, nil
+}
+
+func (x *Parser) conditional() (Expr, error) {
+	expr, err := x.coalesce()
+	if err != nil {
+		return nil, err
+	}
+
+	if x.match(Question) {
+		question := x.previous()
+
+		thenBranch, err := x.expression()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = x.consume(Colon, "expect ':' after then branch of conditional expression")
+		if err != nil {
+			return nil, err
+		}
+
+		elseBranch, err := x.conditional()
+		if err != nil {
+			return nil, err
+		}
+
+		return &ConditionalExpr{
+			Condition: expr,
+			Question:  question,
+			Then:      thenBranch,
+			Else:      elseBranch,
+		}, nil
+	}
+
+	return expr, nil
+}
+
+func (x *Parser) coalesce() (Expr, error) {
+	expr, err := x.or()
+	if err != nil {
+		return nil, err
+	}
+
+	for x.match(QuestionQuestion) {
+		operator := x.previous()
+
+		right, err := x.or()
+		if err != nil {
+			return nil, err
+		}
+
+		expr = &Logical{
+			Left:     expr,
+			Operator: operator,
+			Right:    right,
+		}
 	}
 
 	return expr, nil
@@ -545,7 +866,7 @@ func (x *Parser) comparison() (Expr, error) {
 		return nil, err
 	}
 
-	for x.match(Greater, GreaterEqual, Less, LessEqual) {
+	for x.match(Greater, GreaterEqual, Less, LessEqual, Is) {
 		operator := x.previous()
 		right, err := x.term()
 		if err != nil {
@@ -584,7 +905,7 @@ func (x *Parser) factor() (Expr, error) {
 		return nil, err
 	}
 
-	for x.match(Slash, Star) {
+	for x.match(Slash, Star, Percent) {
 		operator := x.previous()
 
 		right, err := x.unary()
@@ -606,39 +927,145 @@ func (x *Parser) unary() (Expr, error) {
 		return &Unary{operator, right}, err
 	}
 
-	return x.call()
+	if x.match(PlusPlus, MinusMinus) {
+		operator := x.previous()
+
+		target, err := x.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return x.update(target, operator, true)
+	}
+
+	expr, err := x.call()
+	if err != nil {
+		return nil, err
+	}
+
+	if x.match(PlusPlus, MinusMinus) {
+		return x.update(expr, x.previous(), false)
+	}
+
+	return expr, nil
+}
+
+// update makes an increment or decrement of target, which like the target of
+// an assignment must be a variable, property or index.
+func (x *Parser) update(target Expr, operator Token, prefix bool) (Expr, error) {
+	switch target.(type) {
+	case *Variable, *Get, *IndexExpr:
+		return &UpdateExpr{
+			Target:   target,
+			Operator: operator,
+			Prefix:   prefix,
+		}, nil
+	}
+
+	return nil, x.error(operator, "invalid assignment target")
 }
 
 func (x *Parser) call() (Expr, error) {
-	expr, err := x.primary()
+	var expr Expr
+	var err error
+
+	if x.match(Spawn) {
+		expr, err = x.spawn()
+	} else {
+		expr, err = x.primary()
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	// Whether a '?.' can end the chain early.
+	optional := false
+
 	for {
 		if x.match(LeftParen) {
 			expr, err = x.finishCall(expr)
 			if err != nil {
 				return nil, err
 			}
-		} else if x.match(Dot) {
-			name, err := x.consume(Identifier, "expect property name after '.'")
+		} else if x.match(Dot, QuestionDot) {
+			dot := x.previous()
+
+			name, err := x.consume(Identifier, "expect property name after '"+dot.Lexeme+"'")
 			if err != nil {
 				return nil, err
 			}
 
 			expr = &Get{
-				Object: expr,
-				Name:   name,
+				Object:   expr,
+				Name:     name,
+				Optional: dot.Type == QuestionDot,
+			}
+
+			optional = optional || dot.Type == QuestionDot
+		} else if x.match(LeftBracket) {
+			bracket := x.previous()
+
+			index, err := x.expression()
+			if err != nil {
+				return nil, err
+			}
+
+			_, err = x.consume(RightBracket, "expect ']' after index")
+			if err != nil {
+				return nil, err
+			}
+
+			expr = &IndexExpr{
+				Object:  expr,
+				Bracket: bracket,
+				Index:   index,
 			}
 		} else {
 			break
 		}
 	}
 
+	if optional {
+		return &OptionalChainExpr{Chain: expr}, nil
+	}
+
 	return expr, nil
 }
 
+// spawn parses the call after 'spawn', which ends with its first argument
+// list: 'spawn f(x).wait()' waits for the task running f(x).
+func (x *Parser) spawn() (Expr, error) {
+	keyword := x.previous()
+
+	callee, err := x.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for x.match(Dot) {
+		name, err := x.consume(Identifier, "expect property name after '.'")
+		if err != nil {
+			return nil, err
+		}
+
+		callee = &Get{
+			Object: callee,
+			Name:   name,
+		}
+	}
+
+	if !x.match(LeftParen) {
+		return nil, x.error(keyword, "expect a call after 'spawn'")
+	}
+
+	call, err := x.finishCall(callee)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpawnExpr{keyword, call.(*Call)}, nil
+}
+
 func (x *Parser) finishCall(callee Expr) (Expr, error) {
 	var arguments []Expr
 
@@ -682,7 +1109,7 @@ func (x *Parser) primary() (Expr, error) {
 	}
 
 	if x.match(Nil) {
-		return &Literal{Nil}, nil
+		return &Literal{nil}, nil
 	}
 
 	if x.match(Number, String) {
@@ -712,6 +1139,10 @@ func (x *Parser) primary() (Expr, error) {
 		return &ThisExpr{x.previous()}, nil
 	}
 
+	if x.match(Match) {
+		return x.matchExpr()
+	}
+
 	if x.match(Identifier) {
 		return &Variable{x.previous()}, nil
 	}
@@ -733,6 +1164,164 @@ func (x *Parser) primary() (Expr, error) {
 	return nil, x.error(x.peek(), "expect expression")
 }
 
+func (x *Parser) matchExpr() (Expr, error) {
+	keyword := x.previous()
+
+	_, err := x.consume(LeftParen, "expect '(' after 'match'")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := x.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(RightParen, "expect ')' after match value")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = x.consume(LeftBrace, "expect '{' before match cases")
+	if err != nil {
+		return nil, err
+	}
+
+	var arms []*MatchArm
+
+	for !x.check(RightBrace) && !x.isAtEnd() {
+		arm, err := x.matchArm()
+		if err != nil {
+			return nil, err
+		}
+
+		arms = append(arms, arm)
+
+		if !x.match(Comma) {
+			break
+		}
+	}
+
+	_, err = x.consume(RightBrace, "expect '}' after match cases")
+	if err != nil {
+		return nil, err
+	}
+
+	return &MatchExpr{Keyword: keyword, Value: value, Arms: arms}, nil
+}
+
+func (x *Parser) matchArm() (*MatchArm, error) {
+	keyword, err := x.consume(Case, "expect 'case'")
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []Pattern
+
+	for {
+		pattern, err := x.pattern()
+		if err != nil {
+			return nil, err
+		}
+
+		patterns = append(patterns, pattern)
+
+		if !x.match(Pipe) {
+			break
+		}
+	}
+
+	var guard Expr
+
+	if x.match(If) {
+		guard, err = x.expression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = x.consume(FatArrow, "expect '=>' after pattern")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := x.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MatchArm{Keyword: keyword, Patterns: patterns, Guard: guard, Body: body}, nil
+}
+
+func (x *Parser) pattern() (Pattern, error) {
+	switch {
+	case x.match(Number, String):
+		return &LiteralPattern{x.previous(), x.previous().Literal}, nil
+	case x.match(True):
+		return &LiteralPattern{x.previous(), true}, nil
+	case x.match(False):
+		return &LiteralPattern{x.previous(), false}, nil
+	case x.match(Nil):
+		return &LiteralPattern{x.previous(), nil}, nil
+	case x.match(Minus):
+		minus := x.previous()
+
+		number, err := x.consume(Number, "expect number after '-' in pattern")
+		if err != nil {
+			return nil, err
+		}
+
+		return &LiteralPattern{minus, -number.Literal.(float64)}, nil
+	case x.match(Identifier):
+		name := x.previous()
+
+		if x.match(LeftParen) {
+			return x.classPattern(name)
+		}
+
+		if x.match(Colon) {
+			typeName, err := x.consume(Identifier, "expect type name")
+			if err != nil {
+				return nil, err
+			}
+
+			return &BindingPattern{Name: name, Type: &Variable{typeName}}, nil
+		}
+
+		return &BindingPattern{Name: name}, nil
+	}
+
+	return nil, x.error(x.peek(), "expect pattern")
+}
+
+func (x *Parser) classPattern(name Token) (Pattern, error) {
+	paren := x.previous()
+
+	var subpatterns []Pattern
+
+	if !x.check(RightParen) {
+		for {
+			subpattern, err := x.pattern()
+			if err != nil {
+				return nil, err
+			}
+
+			subpatterns = append(subpatterns, subpattern)
+
+			if !x.match(Comma) {
+				break
+			}
+		}
+	}
+
+	_, err := x.consume(RightParen, "expect ')' after subpatterns")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ClassPattern{Class: &Variable{name}, Paren: paren, Subpatterns: subpatterns}, nil
+}
+
 func (x *Parser) match(types ...TokenType) bool {
 	for _, t := range types {
 		if x.check(t) {
@@ -753,6 +1342,14 @@ func (x *Parser) check(t TokenType) bool {
 	return x.peek().Type == t
 }
 
+func (x *Parser) checkNext(t TokenType) bool {
+	if x.isAtEnd() || x.tokens[x.current+1].Type == EOF {
+		return false
+	}
+
+	return x.tokens[x.current+1].Type == t
+}
+
 func (x *Parser) isAtEnd() bool {
 	return x.peek().Type == EOF
 }
@@ -805,11 +1402,11 @@ func (x *Parser) synchronize() {
 	}
 }
 
+func (x *Parser) mark(stmt Stmt, start Token) {
+	x.spans[stmt] = Span{start.Line, x.previous().Line}
+}
+
 // Errors
 func (x *Parser) error(token Token, message string) error {
-	_ = TokenError(token, message)
-
-	return errParse
+	return TokenError(token, message)
 }
-
-var errParse = errors.New("parse error")
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policy lists the host capabilities scripts are granted. Embedders set one
+// on the Interpreter; a nil policy denies them all.
+type Policy struct {
+	// AllowedDirs are the directories the fs natives may touch, including
+	// everything below them.
+	AllowedDirs []string
+	// ReadOnly denies writing to the allowed directories.
+	ReadOnly bool
+	// Env allows reading environment variables.
+	Env bool
+	// Exit allows scripts to end the process.
+	Exit bool
+}
+
+// fullAccessPolicy is what scripts run from the command line get.
+func fullAccessPolicy() *Policy {
+	return &Policy{
+		AllowedDirs: []string{string(filepath.Separator)},
+		Env:         true,
+		Exit:        true,
+	}
+}
+
+// allowsPath reports whether path, after following symbolic links, lies in
+// one of the allowed directories, and if write is set whether it may be
+// written to. It returns the resolved path, which is what callers must open:
+// the original one could reach elsewhere through a link followed by '..'.
+func (x *Policy) allowsPath(path string, write bool) (string, bool) {
+	if x == nil || write && x.ReadOnly {
+		return "", false
+	}
+
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return "", false
+	}
+
+	for _, dir := range x.AllowedDirs {
+		dir, err := resolvePath(dir)
+		if err != nil {
+			continue
+		}
+
+		relative, err := filepath.Rel(dir, resolved)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return resolved, true
+		}
+	}
+
+	return "", false
+}
+
+// maxLinks bounds the symbolic links resolvePath follows, so link cycles end.
+const maxLinks = 255
+
+// resolvePath makes path absolute and resolves its symbolic links one
+// component at a time, applying each '..' to the directory a link led to
+// as the OS does, rather than cleaning the path as text first. Components
+// that don't exist are kept as they are.
+func resolvePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+
+		path = wd + string(filepath.Separator) + path
+	}
+
+	volume := filepath.VolumeName(path)
+	root := volume + string(filepath.Separator)
+
+	resolved := root
+	pending := splitPath(path[len(volume):])
+	links := 0
+
+	for len(pending) > 0 {
+		component := pending[0]
+		pending = pending[1:]
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+
+		info, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			resolved = next
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxLinks {
+			return "", fmt.Errorf("too many links in %s", path)
+		}
+
+		target, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+
+		if filepath.IsAbs(target) {
+			targetVolume := filepath.VolumeName(target)
+			resolved = targetVolume + string(filepath.Separator)
+			target = target[len(targetVolume):]
+		}
+
+		pending = append(splitPath(target), pending...)
+	}
+
+	return resolved, nil
+}
+
+// splitPath splits path at its separators, keeping '.' and '..'.
+func splitPath(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool {
+		return os.IsPathSeparator(uint8(r))
+	})
+}
+
+func (x *Policy) allowsEnv() bool {
+	return x != nil && x.Env
+}
+
+func (x *Policy) allowsExit() bool {
+	return x != nil && x.Exit
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runWithPolicy(t *testing.T, policy *Policy, source string) (string, error) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Stderr: io.Discard, Policy: policy}).Init()
+	err := runSource(interpreter, source)
+
+	return stdout.String(), err
+}
+
+func TestPolicyAllowedDirs(t *testing.T) {
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(outside, filepath.Join(allowed, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A '..' after a link applies to where the link leads, not to the link.
+	if err := os.Mkdir(filepath.Join(allowed, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(outside, filepath.Join(allowed, "sub", "out")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join(allowed, "sub"), filepath.Join(allowed, "in")); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &Policy{AllowedDirs: []string{allowed}}
+
+	output, err := runWithPolicy(t, policy, `
+		fs.writeFile("`+filepath.Join(allowed, "a.txt")+`", "hello");
+		print fs.readFile("`+filepath.Join(allowed, "a.txt")+`");
+		print fs.exists("`+filepath.Join(allowed, "missing.txt")+`");
+		print fs.listDir("`+allowed+`");
+		print fs.readFile("`+allowed+`/in/../a.txt");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "hello\nfalse\n[a.txt, in, link, sub]\nhello\n"; output != expected {
+		t.Errorf("expected output %q, got %q", expected, output)
+	}
+
+	denied := []string{
+		filepath.Join(outside, "secret.txt"),
+		filepath.Join(allowed, "link", "secret.txt"),
+		filepath.Join(allowed, "..", filepath.Base(outside), "secret.txt"),
+		allowed + "/sub/out/../" + filepath.Base(outside) + "/secret.txt",
+	}
+
+	for _, path := range denied {
+		_, err = runWithPolicy(t, policy, `fs.readFile("`+path+`");`)
+
+		var runtimeErr RuntimeError
+		if !errors.As(err, &runtimeErr) || !strings.HasSuffix(runtimeErr.Message, "denied") {
+			t.Errorf("expected reading %s to be denied, got %v", path, err)
+		}
+	}
+}
+
+func TestPolicyReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+
+	policy := &Policy{AllowedDirs: []string{dir}, ReadOnly: true}
+
+	_, err := runWithPolicy(t, policy, `fs.writeFile("`+path+`", "x");`)
+
+	var runtimeErr RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "fs.writeFile: access to '"+path+"' denied" {
+		t.Errorf("expected the write to be denied, got %v", err)
+	}
+
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %s not to be written", path)
+	}
+
+	if _, err = runWithPolicy(t, policy, `fs.exists("`+path+`");`); err != nil {
+		t.Errorf("expected reads to be allowed, got %v", err)
+	}
+}
+
+func TestPolicyExit(t *testing.T) {
+	output, err := runWithPolicy(t, &Policy{Exit: true}, `print 1; os.exit(3); print 2;`)
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Fatalf("expected exit status 3, got %v", err)
+	}
+
+	if output != "1\n" {
+		t.Errorf("expected the script to stop at os.exit, got output %q", output)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import "sort"
+
+// typeName returns the name type() gives the type of value.
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *ClassImpl:
+		return "class"
+	case *TraitImpl:
+		return "trait"
+	case *InstanceImpl:
+		return "instance"
+	case *ListImpl:
+		return "list"
+	case *MapImpl:
+		return "map"
+	case *moduleImpl:
+		return "module"
+	case *GeneratorImpl:
+		return "generator"
+	case *TaskImpl:
+		return "task"
+	case *ChannelImpl:
+		return "channel"
+	case Callable:
+		return "function"
+	}
+
+	return "unknown"
+}
+
+// type(value) names the type of value.
+func funType(_ *Interpreter, arguments []any) (any, error) {
+	return typeName(arguments[0]), nil
+}
+
+// fields(instance) lists the names of the fields of instance, sorted.
+func funFields(interpreter *Interpreter, arguments []any) (any, error) {
+	instance, err := instanceArgument("fields", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := instance.snapshot()
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	return interpreter.stringList(names)
+}
+
+func funHasField(_ *Interpreter, arguments []any) (any, error) {
+	instance, err := instanceArgument("hasField", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := stringArgument("hasField", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	_, ok := instance.field(name)
+
+	return ok, nil
+}
+
+// getField(instance, name) returns a field, unlike a property access never a
+// method.
+func funGetField(_ *Interpreter, arguments []any) (any, error) {
+	instance, err := instanceArgument("getField", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := stringArgument("getField", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := instance.field(name)
+	if !ok {
+		return nil, nativeErrorf("undefined field '%s'", name)
+	}
+
+	return value, nil
+}
+
+func funSetField(interpreter *Interpreter, arguments []any) (any, error) {
+	instance, err := instanceArgument("setField", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := stringArgument("setField", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := instance.field(name); !ok {
+		if err = interpreter.allocate(variableSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if !instance.setField(name, arguments[2]) {
+		return nil, nativeErrorf("can't set field '%s' of a frozen instance", name)
+	}
+
+	return arguments[2], nil
+}
+
+// freeze(instance) stops the fields of instance from being set again, and
+// returns it.
+func funFreeze(_ *Interpreter, arguments []any) (any, error) {
+	instance, err := instanceArgument("freeze", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	instance.freeze()
+
+	return instance, nil
+}
+
+// methods(class) lists the names of the methods class defines or inherits,
+// sorted.
+func funMethods(interpreter *Interpreter, arguments []any) (any, error) {
+	class, err := classArgument("methods", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	var names []string
+	for ; class != nil; class = class.superclass {
+		for name := range class.methods {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	return interpreter.stringList(names)
+}
+
+// superclass(class) returns the class class inherits from, or nil.
+func funSuperclass(_ *Interpreter, arguments []any) (any, error) {
+	class, err := classArgument("superclass", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if class.superclass == nil {
+		return nil, nil
+	}
+
+	return class.superclass, nil
+}
+
+// arity(fn) returns how many arguments fn takes, or -1 for natives taking any
+// number.
+func funArity(interpreter *Interpreter, arguments []any) (any, error) {
+	callee := arguments[0]
+	if method := interpreter.operator(callee, "__call"); method != nil {
+		callee = method
+	}
+
+	fn, ok := callee.(Callable)
+	if !ok {
+		return nil, nativeErrorf("argument 1 to 'arity' must be callable")
+	}
+
+	return float64(fn.Arity()), nil
+}
+
+// stringList returns names sorted, as a list.
+func (x *Interpreter) stringList(names []string) (any, error) {
+	sort.Strings(names)
+
+	if err := x.allocateList(len(names)); err != nil {
+		return nil, err
+	}
+
+	elements := make([]any, 0, len(names))
+	for _, name := range names {
+		elements = append(elements, name)
+	}
+
+	return newList(elements), nil
+}
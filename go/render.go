@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stringify returns value as print shows it. Instances show as what their
+// __str or toString method returns, if they have one, and as their class
+// and fields otherwise.
+func (x *Interpreter) stringify(value any) (string, error) {
+	return (&renderer{interpreter: x, methods: true}).render(value)
+}
+
+// inspect is stringify for debuggers and error messages, which mustn't run
+// Lox code: it shows every instance as its class and fields.
+func (x *Interpreter) inspect(value any) string {
+	s, _ := (&renderer{interpreter: x}).render(value)
+
+	return s
+}
+
+// renderer writes values out, showing those that contain themselves as
+// "..." the second time around.
+type renderer struct {
+	interpreter *Interpreter
+	methods     bool // whether to call __str and toString methods
+	visiting    map[any]bool
+}
+
+func (x *renderer) render(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "nil", nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case *ListImpl:
+		return x.container(value, "[", "]", func() ([]string, error) {
+			return x.all(value.snapshot())
+		})
+	case *MapImpl:
+		keys, values := value.entries()
+
+		return x.container(value, "{", "}", func() ([]string, error) {
+			return x.entries(keys, values)
+		})
+	case *InstanceImpl:
+		if x.methods {
+			if s, ok, err := x.method(value); ok || err != nil {
+				return s, err
+			}
+		}
+
+		fields := value.snapshot()
+
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		values := make([]any, 0, len(names))
+		for _, name := range names {
+			values = append(values, fields[name])
+		}
+
+		return x.container(value, value.klass.name+" {", "}", func() ([]string, error) {
+			return x.entries(names, values)
+		})
+	case fmt.Stringer:
+		return value.String(), nil
+	}
+
+	return fmt.Sprintf("%v", value), nil
+}
+
+// method returns what the __str or toString method of instance returns, and
+// false if it has neither.
+func (x *renderer) method(instance *InstanceImpl) (string, bool, error) {
+	method := x.interpreter.operator(instance, "__str")
+	if method == nil {
+		method = x.interpreter.operator(instance, "toString")
+	}
+
+	if method == nil || method.Arity() != 0 {
+		return "", false, nil
+	}
+
+	name := method.declaration.Name
+
+	result, err := x.interpreter.call(method, nil, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	s, ok := result.(string)
+	if !ok {
+		return "", false, RuntimeError{"'" + name.Lexeme + "' must return a string", name}
+	}
+
+	return s, true, nil
+}
+
+func (x *renderer) container(value any, open, close string, elements func() ([]string, error)) (string, error) {
+	if x.visiting[value] {
+		return open + "..." + close, nil
+	}
+
+	if x.visiting == nil {
+		x.visiting = make(map[any]bool)
+	}
+
+	x.visiting[value] = true
+	defer delete(x.visiting, value)
+
+	rendered, err := elements()
+	if err != nil {
+		return "", err
+	}
+
+	return open + strings.Join(rendered, ", ") + close, nil
+}
+
+func (x *renderer) all(values []any) ([]string, error) {
+	rendered := make([]string, 0, len(values))
+	for _, value := range values {
+		s, err := x.render(value)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered = append(rendered, s)
+	}
+
+	return rendered, nil
+}
+
+func (x *renderer) entries(keys []string, values []any) ([]string, error) {
+	rendered, err := x.all(values)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		rendered[i] = key + ": " + rendered[i]
+	}
+
+	return rendered, nil
+}
+
+// str(value) returns value as print would show it.
+func funStr(interpreter *Interpreter, arguments []any) (any, error) {
+	s, err := interpreter.stringify(arguments[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = interpreter.allocateString(len(s)); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
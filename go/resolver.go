@@ -6,6 +6,7 @@ const (
 	classTypeNone = iota
 	classTypeClass
 	classTypeSubclass
+	classTypeTrait
 )
 
 type functionType int
@@ -17,22 +18,62 @@ const (
 	funcTypeInitializer
 )
 
+// Resolver binds each use of a local variable to the scope it was declared in,
+// counted outward from the use. It keeps the result to itself, so resolving a
+// program doesn't touch any interpreter running another.
 type Resolver struct {
-	interpreter     *Interpreter
+	locals          map[Expr]int
 	scopes          mapStack
 	currentFunction functionType
+	inGenerator     bool
 	currentClass    classType
+	listener        resolutionListener
+
+	// classes holds the kinds of the program's global classes and traits,
+	// which can be declared after the patterns that name them.
+	classes map[string]classType
+}
+
+// resolutionListener is told about every name the resolver declares and every
+// use it binds, which is what editor tooling needs to navigate between them,
+// and about the uses that assign to the variable. Uses of globals are
+// reported with local set to false and no declaration, since globals can be
+// declared after the code that uses them.
+type resolutionListener interface {
+	declared(name Token, node Stmt, local bool)
+	referenced(name Token, declaration Token, local bool)
+	assigned(name Token, declaration Token, local bool)
 }
 
-func NewResolver(interpreter *Interpreter) *Resolver {
+func NewResolver() *Resolver {
 	return &Resolver{
-		interpreter: interpreter,
-		scopes:      mapStack{},
+		locals:  make(map[Expr]int),
+		scopes:  mapStack{},
+		classes: make(map[string]classType),
 	}
 }
 
-func (r *Resolver) Resolve(statements []Stmt) error {
-	return r.resolveStmts(statements)
+func (r *Resolver) listen(listener resolutionListener) {
+	r.listener = listener
+}
+
+// Resolve returns the scope distance of every local variable use in
+// statements; uses of globals are left out.
+func (r *Resolver) Resolve(statements []Stmt) (map[Expr]int, error) {
+	for _, statement := range statements {
+		switch statement := statement.(type) {
+		case *ClassStmt:
+			r.classes[statement.Name.Lexeme] = classTypeClass
+		case *TraitStmt:
+			r.classes[statement.Name.Lexeme] = classTypeTrait
+		}
+	}
+
+	if err := r.resolveStmts(statements); err != nil {
+		return nil, err
+	}
+
+	return r.locals, nil
 }
 
 // region statements
@@ -55,11 +96,15 @@ func (r *Resolver) VisitPrintStmt(stmt *PrintStmt) error {
 }
 
 func (r *Resolver) VisitVarStmt(stmt *VarStmt) error {
-	err := r.declare(stmt.Name)
+	err := r.declare(stmt.Name, stmt)
 	if err != nil {
 		return err
 	}
 
+	if stmt.Constant && len(r.scopes) > 0 {
+		r.scopes.Peek()[stmt.Name.Lexeme].constant = true
+	}
+
 	if stmt.Initializer != nil {
 		err = r.resolveExpr(stmt.Initializer)
 		if err != nil {
@@ -120,8 +165,67 @@ func (r *Resolver) VisitWhileStmt(stmt *WhileStmt) error {
 	return nil
 }
 
+func (r *Resolver) VisitForStmt(stmt *ForStmt) error {
+	r.beginScope()
+
+	if stmt.Initializer != nil {
+		err := r.resolveStmt(stmt.Initializer)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stmt.Condition != nil {
+		err := r.resolveExpr(stmt.Condition)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stmt.Increment != nil {
+		err := r.resolveExpr(stmt.Increment)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := r.resolveStmt(stmt.Body)
+	if err != nil {
+		return err
+	}
+
+	r.endScope()
+
+	return nil
+}
+
+func (r *Resolver) VisitForInStmt(stmt *ForInStmt) error {
+	err := r.resolveExpr(stmt.Iterable)
+	if err != nil {
+		return err
+	}
+
+	r.beginScope()
+
+	err = r.declare(stmt.Name, stmt)
+	if err != nil {
+		return err
+	}
+
+	r.define(stmt.Name)
+
+	err = r.resolveStmt(stmt.Body)
+	if err != nil {
+		return err
+	}
+
+	r.endScope()
+
+	return nil
+}
+
 func (r *Resolver) VisitFunctionStmt(stmt *FunctionStmt) error {
-	err := r.declare(stmt.Name)
+	err := r.declare(stmt.Name, stmt)
 	if err != nil {
 		return err
 	}
@@ -146,6 +250,10 @@ func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) error {
 			return TokenError(stmt.Keyword, "can't return a value from an initializer")
 		}
 
+		if r.inGenerator {
+			return TokenError(stmt.Keyword, "can't return a value from a generator")
+		}
+
 		err := r.resolveExpr(stmt.Value)
 		if err != nil {
 			return err
@@ -155,16 +263,38 @@ func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) error {
 	return nil
 }
 
+func (r *Resolver) VisitYieldStmt(stmt *YieldStmt) error {
+	if r.currentFunction == funcTypeNone {
+		return TokenError(stmt.Keyword, "can't yield from top-level code")
+	}
+
+	if r.currentFunction == funcTypeInitializer {
+		return TokenError(stmt.Keyword, "can't yield from an initializer")
+	}
+
+	if stmt.Value != nil {
+		return r.resolveExpr(stmt.Value)
+	}
+
+	return nil
+}
+
 func (r *Resolver) VisitClassStmt(stmt *ClassStmt) error {
 	enclosingClass := r.currentClass
 	r.currentClass = classTypeClass
 
-	err := r.declare(stmt.Name)
+	err := r.declare(stmt.Name, stmt)
 	if err != nil {
 		return err
 	}
 
+	// Patterns and 'is' take these names for the built-in types.
+	if _, ok := patternTypes[stmt.Name.Lexeme]; ok {
+		return TokenError(stmt.Name, "can't name a class after the built-in type '"+stmt.Name.Lexeme+"'")
+	}
+
 	r.define(stmt.Name)
+	r.markClass(stmt.Name, classTypeClass)
 
 	if stmt.Superclass != nil && stmt.Name.Lexeme == stmt.Superclass.Name.Lexeme {
 		return TokenError(stmt.Superclass.Name, "a class can't inherit from itself")
@@ -179,20 +309,81 @@ func (r *Resolver) VisitClassStmt(stmt *ClassStmt) error {
 		}
 	}
 
+	for _, trait := range stmt.Traits {
+		err = r.resolveExpr(trait)
+		if err != nil {
+			return err
+		}
+	}
+
 	if stmt.Superclass != nil {
 		r.beginScope()
-		r.scopes.Peek()["super"] = true
+		r.scopes.Peek()["super"] = &localVariable{defined: true}
 	}
 
+	err = r.resolveMethods(stmt.Methods)
+	if err != nil {
+		return err
+	}
+
+	if stmt.Superclass != nil {
+		r.endScope()
+	}
+
+	r.currentClass = enclosingClass
+
+	return nil
+}
+
+// VisitTraitStmt resolves trait methods as if in a subclass: 'super' in
+// them refers to the superclass of the class the trait is mixed into.
+func (r *Resolver) VisitTraitStmt(stmt *TraitStmt) error {
+	enclosingClass := r.currentClass
+	r.currentClass = classTypeTrait
+
+	err := r.declare(stmt.Name, stmt)
+	if err != nil {
+		return err
+	}
+
+	// Patterns and 'is' take these names for the built-in types.
+	if _, ok := patternTypes[stmt.Name.Lexeme]; ok {
+		return TokenError(stmt.Name, "can't name a trait after the built-in type '"+stmt.Name.Lexeme+"'")
+	}
+
+	r.define(stmt.Name)
+	r.markClass(stmt.Name, classTypeTrait)
+
+	r.beginScope()
+	r.scopes.Peek()["super"] = &localVariable{defined: true}
+
+	err = r.resolveMethods(stmt.Methods)
+	if err != nil {
+		return err
+	}
+
+	r.endScope()
+
+	r.currentClass = enclosingClass
+
+	return nil
+}
+
+func (r *Resolver) resolveMethods(methods []*FunctionStmt) error {
 	r.beginScope()
-	r.scopes.Peek()["this"] = true
+	r.scopes.Peek()["this"] = &localVariable{defined: true}
 
-	for _, method := range stmt.Methods {
+	for _, method := range methods {
 		declaration := funcTypeMethod
 		if method.Name.Lexeme == "init" {
 			declaration = funcTypeInitializer
 		}
 
+		err := checkOperatorArity(method)
+		if err != nil {
+			return err
+		}
+
 		err = r.resolveFunction(method, declaration)
 		if err != nil {
 			return err
@@ -201,12 +392,6 @@ func (r *Resolver) VisitClassStmt(stmt *ClassStmt) error {
 
 	r.endScope()
 
-	if stmt.Superclass != nil {
-		r.endScope()
-	}
-
-	r.currentClass = enclosingClass
-
 	return nil
 }
 
@@ -251,7 +436,7 @@ func (r *Resolver) VisitUnaryExpr(expr *Unary) (any, error) {
 
 func (r *Resolver) VisitVariableExpr(expr *Variable) (any, error) {
 	if len(r.scopes) > 0 {
-		if value, ok := r.scopes.Peek()[expr.Name.Lexeme]; ok && value == false {
+		if variable, ok := r.scopes.Peek()[expr.Name.Lexeme]; ok && !variable.defined {
 			return nil, TokenError(expr.Name, "can't read local variable in its own initializer")
 		}
 	}
@@ -265,11 +450,19 @@ func (r *Resolver) VisitAssignExpr(expr *Assign) (any, error) {
 		return nil, err
 	}
 
+	// Global constants are checked when the assignment runs, since the REPL
+	// may declare them again.
+	if variable := r.lookup(expr.Name); variable != nil && variable.constant {
+		return nil, TokenError(expr.Name, "can't assign to constant '"+expr.Name.Lexeme+"'")
+	}
+
 	err = r.resolveLocal(expr, expr.Name)
 	if err != nil {
 		return nil, err
 	}
 
+	r.assigned(expr.Name)
+
 	return nil, nil
 }
 
@@ -287,6 +480,39 @@ func (r *Resolver) VisitLogicalExpr(expr *Logical) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitConditionalExpr(expr *ConditionalExpr) (any, error) {
+	err := r.resolveExpr(expr.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.resolveExpr(expr.Then)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Else)
+}
+
+func (r *Resolver) VisitUpdateExpr(expr *UpdateExpr) (any, error) {
+	variable, ok := expr.Target.(*Variable)
+	if !ok {
+		return nil, r.resolveExpr(expr.Target)
+	}
+
+	if local := r.lookup(variable.Name); local != nil && local.constant {
+		return nil, TokenError(variable.Name, "can't assign to constant '"+variable.Name.Lexeme+"'")
+	}
+
+	if err := r.resolveExpr(variable); err != nil {
+		return nil, err
+	}
+
+	r.assigned(variable.Name)
+
+	return nil, nil
+}
+
 func (r *Resolver) VisitCallExpr(expr *Call) (any, error) {
 	err := r.resolveExpr(expr.Callee)
 	if err != nil {
@@ -303,6 +529,33 @@ func (r *Resolver) VisitCallExpr(expr *Call) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitSpawnExpr(expr *SpawnExpr) (any, error) {
+	return r.VisitCallExpr(expr.Call)
+}
+
+func (r *Resolver) VisitIndexExpr(expr *IndexExpr) (any, error) {
+	err := r.resolveExpr(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Index)
+}
+
+func (r *Resolver) VisitSetIndexExpr(expr *SetIndexExpr) (any, error) {
+	err := r.resolveExpr(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.resolveExpr(expr.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, r.resolveExpr(expr.Index)
+}
+
 func (r *Resolver) VisitGetExpr(expr *Get) (any, error) {
 	err := r.resolveExpr(expr.Object)
 	if err != nil {
@@ -312,6 +565,10 @@ func (r *Resolver) VisitGetExpr(expr *Get) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitOptionalChainExpr(expr *OptionalChainExpr) (any, error) {
+	return nil, r.resolveExpr(expr.Chain)
+}
+
 func (r *Resolver) VisitSetExpr(expr *Set) (any, error) {
 	err := r.resolveExpr(expr.Value)
 	if err != nil {
@@ -339,10 +596,65 @@ func (r *Resolver) VisitThisExpr(expr *ThisExpr) (any, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitMatchExpr(expr *MatchExpr) (any, error) {
+	err := r.resolveExpr(expr.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, arm := range expr.Arms {
+		var bindings []Token
+
+		// Classes and types are looked up outside the arm's scope, before
+		// its variables are bound.
+		for _, pattern := range arm.Patterns {
+			for _, variable := range patternVariables(pattern) {
+				if err = r.resolveExpr(variable); err != nil {
+					return nil, err
+				}
+			}
+
+			if err = r.checkBindings(pattern); err != nil {
+				return nil, err
+			}
+
+			bindings = append(bindings, patternBindings(pattern)...)
+		}
+
+		if len(arm.Patterns) > 1 && len(bindings) > 0 {
+			return nil, TokenError(bindings[0], "can't bind variables in alternative patterns")
+		}
+
+		r.beginScope()
+
+		for _, name := range bindings {
+			if err = r.declare(name, nil); err != nil {
+				return nil, err
+			}
+
+			r.define(name)
+		}
+
+		if arm.Guard != nil {
+			if err = r.resolveExpr(arm.Guard); err != nil {
+				return nil, err
+			}
+		}
+
+		if err = r.resolveExpr(arm.Body); err != nil {
+			return nil, err
+		}
+
+		r.endScope()
+	}
+
+	return nil, nil
+}
+
 func (r *Resolver) VisitSuperExpr(expr *SuperExpr) (any, error) {
 	if r.currentClass == classTypeNone {
 		return nil, TokenError(expr.Keyword, "can't use 'super' outside of a class")
-	} else if r.currentClass != classTypeSubclass {
+	} else if r.currentClass == classTypeClass {
 		return nil, TokenError(expr.Keyword, "can't use 'super' in a class with no superclass")
 	}
 
@@ -374,22 +686,109 @@ func (r *Resolver) resolveExpr(expression Expr) error {
 
 func (r *Resolver) resolveLocal(expression Expr, name Token) error {
 	for i := len(r.scopes) - 1; i >= 0; i-- {
-		if _, ok := r.scopes[i][name.Lexeme]; ok {
-			r.interpreter.Resolve(expression, len(r.scopes)-1-i)
+		if variable, ok := r.scopes[i][name.Lexeme]; ok {
+			r.locals[expression] = len(r.scopes) - 1 - i
+
+			if r.listener != nil && variable.name.Line > 0 {
+				r.listener.referenced(name, variable.name, true)
+			}
+
+			return nil
 		}
 	}
+
+	if r.listener != nil {
+		r.listener.referenced(name, Token{}, false)
+	}
+
+	return nil
+}
+
+// assigned tells the listener, if any, that name is assigned to.
+func (r *Resolver) assigned(name Token) {
+	if r.listener == nil {
+		return
+	}
+
+	if variable := r.lookup(name); variable != nil {
+		r.listener.assigned(name, variable.name, true)
+	} else {
+		r.listener.assigned(name, Token{}, false)
+	}
+}
+
+// lookup returns the local variable name refers to, or nil if it's global.
+func (r *Resolver) lookup(name Token) *localVariable {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if variable, ok := r.scopes[i][name.Lexeme]; ok {
+			return variable
+		}
+	}
+
+	return nil
+}
+
+// markClass records that the local name was declared by a class or trait.
+func (r *Resolver) markClass(name Token, kind classType) {
+	if len(r.scopes) > 0 {
+		r.scopes.Peek()[name.Lexeme].class = kind
+	}
+}
+
+// classKind reports whether name refers to a class or trait, and which.
+func (r *Resolver) classKind(name Token) classType {
+	if variable := r.lookup(name); variable != nil {
+		return variable.class
+	}
+
+	return r.classes[name.Lexeme]
+}
+
+// checkBindings rejects a binding pattern named after a type, which would
+// bind any value rather than test for the type.
+func (r *Resolver) checkBindings(pattern Pattern) error {
+	switch pattern := pattern.(type) {
+	case *BindingPattern:
+		name := pattern.Name.Lexeme
+
+		if pattern.Type != nil {
+			return nil
+		}
+
+		if _, ok := patternTypes[name]; ok {
+			return TokenError(pattern.Name, "'"+name+"' would bind any value; write 'x: "+name+"' to match the type")
+		}
+
+		switch r.classKind(pattern.Name) {
+		case classTypeClass:
+			return TokenError(pattern.Name, "'"+name+"' would bind any value; write '"+name+"()' or 'x: "+name+"' to match its instances")
+		case classTypeTrait:
+			return TokenError(pattern.Name, "'"+name+"' would bind any value; write 'x: "+name+"' to match its instances")
+		}
+	case *ClassPattern:
+		for _, subpattern := range pattern.Subpatterns {
+			if err := r.checkBindings(subpattern); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
 func (r *Resolver) beginScope() {
-	r.scopes.Push(make(map[string]bool))
+	r.scopes.Push(make(map[string]*localVariable))
 }
 
 func (r *Resolver) endScope() {
 	r.scopes.Pop()
 }
 
-func (r *Resolver) declare(name Token) error {
+func (r *Resolver) declare(name Token, node Stmt) error {
+	if r.listener != nil {
+		r.listener.declared(name, node, len(r.scopes) > 0)
+	}
+
 	if len(r.scopes) == 0 {
 		return nil
 	}
@@ -400,7 +799,7 @@ func (r *Resolver) declare(name Token) error {
 		return TokenError(name, "already a variable with this name in this scope")
 	}
 
-	scope[name.Lexeme] = false
+	scope[name.Lexeme] = &localVariable{name: name}
 
 	return nil
 }
@@ -411,17 +810,19 @@ func (r *Resolver) define(name Token) {
 	}
 
 	scope := r.scopes.Peek()
-	scope[name.Lexeme] = true
+	scope[name.Lexeme].defined = true
 }
 
 func (r *Resolver) resolveFunction(fn *FunctionStmt, funcType functionType) error {
 	enclosingFunction := r.currentFunction
+	enclosingGenerator := r.inGenerator
 	r.currentFunction = funcType
+	r.inGenerator = fn.IsGenerator
 
 	r.beginScope()
 
 	for _, param := range fn.Params {
-		err := r.declare(param)
+		err := r.declare(param, fn)
 		if err != nil {
 			return err
 		}
@@ -437,6 +838,7 @@ func (r *Resolver) resolveFunction(fn *FunctionStmt, funcType functionType) erro
 	r.endScope()
 
 	r.currentFunction = enclosingFunction
+	r.inGenerator = enclosingGenerator
 
 	return nil
 }
@@ -444,13 +846,20 @@ func (r *Resolver) resolveFunction(fn *FunctionStmt, funcType functionType) erro
 // endregion
 
 // region helper data structures
-type mapStack []map[string]bool
+type localVariable struct {
+	name     Token // declaring token, zero for 'this' and 'super'
+	defined  bool
+	constant bool
+	class    classType // declared by a class or trait, or classTypeNone
+}
+
+type mapStack []map[string]*localVariable
 
-func (s *mapStack) Push(m map[string]bool) {
+func (s *mapStack) Push(m map[string]*localVariable) {
 	*s = append(*s, m)
 }
 
-func (s *mapStack) Pop() map[string]bool {
+func (s *mapStack) Pop() map[string]*localVariable {
 	if len(*s) > 0 {
 		v := (*s)[len(*s)-1]
 		*s = (*s)[:len(*s)-1]
@@ -460,7 +869,7 @@ func (s *mapStack) Pop() map[string]bool {
 	return nil
 }
 
-func (s *mapStack) Peek() map[string]bool {
+func (s *mapStack) Peek() map[string]*localVariable {
 	if len(*s) > 0 {
 		v := (*s)[len(*s)-1]
 		return v
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const isolationProgram = `
+class Counter {
+  init(start) {
+    this.count = start;
+  }
+
+  next() {
+    this.count = this.count + 1;
+    return this.count;
+  }
+}
+
+fun makeAdder(n) {
+  fun add(x) {
+    return x + n;
+  }
+  return add;
+}
+
+var counter = Counter(id * 1000);
+var add = makeAdder(id);
+var words = list();
+
+for (var i = 0; i < 200; i = i + 1) {
+  counter.next();
+  words.push(add(i));
+}
+
+math.seed(id);
+var m = map();
+m.set("id", id);
+m.set("last", counter.count);
+m.set("random", math.floor(math.random() * 1000));
+
+print json.stringify(m);
+print " ".join(words).len();
+`
+
+// TestIsolatedRuntimes runs one parsed program on many interpreters at once;
+// run it with -race to check they share no mutable state.
+func TestIsolatedRuntimes(t *testing.T) {
+	const runtimes = 8
+
+	var outputs [runtimes]string
+
+	var wg sync.WaitGroup
+
+	for id := 0; id < runtimes; id++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			var stdout bytes.Buffer
+
+			source := fmt.Sprintf("var id = %d;\n%s", id, isolationProgram)
+
+			interpreter := (&Interpreter{Stdout: &stdout, Stderr: io.Discard, Stdin: strings.NewReader("")}).Init()
+			if err := runSource(interpreter, source); err != nil {
+				t.Errorf("runtime %d: %v", id, err)
+			}
+
+			outputs[id] = stdout.String()
+		}(id)
+	}
+
+	wg.Wait()
+
+	for id := 0; id < runtimes; id++ {
+		var stdout bytes.Buffer
+
+		source := fmt.Sprintf("var id = %d;\n%s", id, isolationProgram)
+
+		interpreter := (&Interpreter{Stdout: &stdout, Stderr: io.Discard, Stdin: strings.NewReader("")}).Init()
+		if err := runSource(interpreter, source); err != nil {
+			t.Fatalf("runtime %d: %v", id, err)
+		}
+
+		if outputs[id] != stdout.String() {
+			t.Errorf("runtime %d: concurrent output %q differs from sequential output %q", id, outputs[id], stdout.String())
+		}
+	}
+}
+
+// TestSharedStatements resolves and runs the same syntax tree on several
+// interpreters at once.
+func TestSharedStatements(t *testing.T) {
+	statements, err := parse(`
+		fun fib(n) {
+		  if (n < 2) return n;
+		  return fib(n - 1) + fib(n - 2);
+		}
+		var total = 0;
+		for (var i = 0; i < 15; i = i + 1) total = total + fib(i);
+		print total;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var stdout bytes.Buffer
+
+			interpreter := (&Interpreter{Stdout: &stdout}).Init()
+
+			if err := interpreter.Resolve(statements); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := interpreter.Interpret(statements); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if stdout.String() != "986\n" {
+				t.Errorf("expected 986, got %q", stdout.String())
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// TestInteractiveRedeclaresConstants checks that, unlike a script, the REPL
+// lets a declaration replace a global constant.
+func TestInteractiveRedeclaresConstants(t *testing.T) {
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Interactive: true}).Init()
+
+	for _, line := range []string{`const a = 1;`, `var a = 2;`, `a = 3;`, `print a;`} {
+		if err := runSource(interpreter, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if stdout.String() != "3\n" {
+		t.Errorf("expected the constant to be replaced, got %q", stdout.String())
+	}
+}
+
+// TestRuntimeErrorsGoToStderr checks that a runtime error is reported apart
+// from what the program printed before it.
+func TestRuntimeErrorsGoToStderr(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout, Stderr: &stderr}).Init()
+
+	if err := runSource(interpreter, "print 1;\nnil();\n"); err == nil {
+		t.Fatal("expected a runtime error")
+	}
+
+	if stdout.String() != "1\n" {
+		t.Errorf("expected only the program's output on stdout, got %q", stdout.String())
+	}
+
+	if stderr.String() != "can only call functions and classes\n[line 2]\n" {
+		t.Errorf("unexpected report %q", stderr.String())
+	}
+}
@@ -16,37 +16,62 @@ func NewScanner(source string) Scanner {
 	}
 }
 
+// NewTriviaScanner returns a scanner that keeps comments in the token stream
+// as Comment tokens instead of discarding them. The parser doesn't accept
+// them, so tools like the formatter split them out before parsing.
+func NewTriviaScanner(source string) Scanner {
+	s := NewScanner(source).(*scanner)
+	s.keepComments = true
+
+	return s
+}
+
 var keywords = map[string]TokenType{
 	"and":    And,
+	"case":   Case,
 	"class":  Class,
+	"const":  Const,
 	"else":   Else,
 	"false":  False,
 	"for":    For,
 	"fun":    Fun,
 	"if":     If,
+	"in":     In,
+	"is":     Is,
+	"match":  Match,
 	"nil":    Nil,
 	"or":     Or,
 	"print":  Print,
 	"return": Return,
+	"spawn":  Spawn,
 	"super":  Super,
 	"this":   This,
+	"trait":  Trait,
 	"true":   True,
 	"var":    Var,
 	"while":  While,
+	"with":   With,
+	"yield":  Yield,
 }
 
 type scanner struct {
-	source  string
-	tokens  []Token
-	start   int
-	current int
-	line    int
+	source       string
+	tokens       []Token
+	start        int
+	current      int
+	line         int
+	lineStart    int // offset of the first character of the current line
+	startLine    int // line and column where the current lexeme begins
+	startColumn  int
+	keepComments bool
 }
 
 func (x *scanner) ScanTokens() ([]Token, error) {
 	for !x.isAtEnd() {
 		// We are at the beginning of the next lexeme.
 		x.start = x.current
+		x.startLine = x.line
+		x.startColumn = x.column()
 
 		err := x.scanToken()
 		if err != nil {
@@ -54,7 +79,7 @@ func (x *scanner) ScanTokens() ([]Token, error) {
 		}
 	}
 
-	x.tokens = append(x.tokens, Token{EOF, "", nil, x.line})
+	x.tokens = append(x.tokens, Token{EOF, "", nil, x.line, x.column()})
 
 	return x.tokens, nil
 }
@@ -73,18 +98,56 @@ func (x *scanner) scanToken() error {
 		x.addToken(LeftBrace, nil)
 	case '}':
 		x.addToken(RightBrace, nil)
+	case '[':
+		x.addToken(LeftBracket, nil)
+	case ']':
+		x.addToken(RightBracket, nil)
 	case ',':
 		x.addToken(Comma, nil)
+	case ':':
+		x.addToken(Colon, nil)
+	case '|':
+		x.addToken(Pipe, nil)
 	case '.':
 		x.addToken(Dot, nil)
 	case '-':
-		x.addToken(Minus, nil)
+		if x.match('-') {
+			x.addToken(MinusMinus, nil)
+		} else if x.match('=') {
+			x.addToken(MinusEqual, nil)
+		} else {
+			x.addToken(Minus, nil)
+		}
 	case '+':
-		x.addToken(Plus, nil)
+		if x.match('+') {
+			x.addToken(PlusPlus, nil)
+		} else if x.match('=') {
+			x.addToken(PlusEqual, nil)
+		} else {
+			x.addToken(Plus, nil)
+		}
 	case ';':
 		x.addToken(Semicolon, nil)
 	case '*':
-		x.addToken(Star, nil)
+		if x.match('=') {
+			x.addToken(StarEqual, nil)
+		} else {
+			x.addToken(Star, nil)
+		}
+	case '%':
+		if x.match('=') {
+			x.addToken(PercentEqual, nil)
+		} else {
+			x.addToken(Percent, nil)
+		}
+	case '?':
+		if x.match('.') {
+			x.addToken(QuestionDot, nil)
+		} else if x.match('?') {
+			x.addToken(QuestionQuestion, nil)
+		} else {
+			x.addToken(Question, nil)
+		}
 	case '!':
 		if x.match('=') {
 			x.addToken(BangEqual, nil)
@@ -94,6 +157,8 @@ func (x *scanner) scanToken() error {
 	case '=':
 		if x.match('=') {
 			x.addToken(EqualEqual, nil)
+		} else if x.match('>') {
+			x.addToken(FatArrow, nil)
 		} else {
 			x.addToken(Equal, nil)
 		}
@@ -114,6 +179,8 @@ func (x *scanner) scanToken() error {
 			for x.peek() != '\n' && !x.isAtEnd() {
 				x.advance()
 			}
+
+			x.addComment()
 		} else if x.match('*') {
 			stack := []struct{}{{}}
 
@@ -134,8 +201,19 @@ func (x *scanner) scanToken() error {
 					continue
 				}
 
+				if x.peek() == '\n' {
+					x.advance()
+					x.newline()
+
+					continue
+				}
+
 				x.advance()
 			}
+
+			x.addComment()
+		} else if x.match('=') {
+			x.addToken(SlashEqual, nil)
 		} else {
 			x.addToken(Slash, nil)
 		}
@@ -144,7 +222,7 @@ func (x *scanner) scanToken() error {
 	case '\t':
 		break
 	case '\n':
-		x.line++
+		x.newline()
 	case '"':
 		err = x.string()
 	default:
@@ -169,7 +247,26 @@ func (x *scanner) advance() uint8 {
 
 func (x *scanner) addToken(tokenType TokenType, literal any) {
 	text := x.source[x.start:x.current]
-	x.tokens = append(x.tokens, Token{tokenType, text, literal, x.line})
+	x.tokens = append(x.tokens, Token{tokenType, text, literal, x.startLine, x.startColumn})
+}
+
+// addComment records the comment ending at the current position when the
+// scanner keeps trivia.
+func (x *scanner) addComment() {
+	if x.keepComments {
+		x.addToken(Comment, nil)
+	}
+}
+
+// newline must be called after consuming a '\n' to keep line and column
+// tracking right.
+func (x *scanner) newline() {
+	x.line++
+	x.lineStart = x.current
+}
+
+func (x *scanner) column() int {
+	return x.current - x.lineStart + 1
 }
 
 func (x *scanner) match(expected uint8) bool {
@@ -182,6 +279,10 @@ func (x *scanner) match(expected uint8) bool {
 	return true
 }
 
+func (x *scanner) previous() uint8 {
+	return x.source[x.current-1]
+}
+
 func (x *scanner) peek() uint8 {
 	if x.isAtEnd() {
 		return '\x00'
@@ -200,11 +301,11 @@ func (x *scanner) peekNext() uint8 {
 
 func (x *scanner) string() error {
 	for x.peek() != '"' && !x.isAtEnd() {
-		if x.peek() == '\n' {
-			x.line++
-		}
-
 		x.advance()
+
+		if x.previous() == '\n' {
+			x.newline()
+		}
 	}
 
 	if x.isAtEnd() {
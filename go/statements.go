@@ -8,9 +8,13 @@ type StmtVisitor interface {
 	VisitBlockStmt(stmt *BlockStmt) error
 	VisitIfStmt(stmt *IfStmt) error
 	VisitWhileStmt(stmt *WhileStmt) error
+	VisitForStmt(stmt *ForStmt) error
+	VisitForInStmt(stmt *ForInStmt) error
 	VisitFunctionStmt(stmt *FunctionStmt) error
 	VisitReturnStmt(stmt *ReturnStmt) error
 	VisitClassStmt(stmt *ClassStmt) error
+	VisitTraitStmt(stmt *TraitStmt) error
+	VisitYieldStmt(stmt *YieldStmt) error
 }
 
 type Stmt interface {
@@ -35,7 +39,9 @@ func (x *PrintStmt) Accept(visitor StmtVisitor) error {
 
 type VarStmt struct {
 	Name        Token
+	Type        *TypeAnnotation // nil when not annotated
 	Initializer Expr
+	Constant    bool // declared with 'const', so it can't be assigned to
 }
 
 func (x *VarStmt) Accept(visitor StmtVisitor) error {
@@ -69,10 +75,36 @@ func (x *WhileStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitWhileStmt(x)
 }
 
+type ForStmt struct {
+	Keyword     Token
+	Initializer Stmt
+	Condition   Expr
+	Increment   Expr
+	Body        Stmt
+}
+
+func (x *ForStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitForStmt(x)
+}
+
+type ForInStmt struct {
+	Keyword  Token
+	Name     Token
+	Iterable Expr
+	Body     Stmt
+}
+
+func (x *ForInStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitForInStmt(x)
+}
+
 type FunctionStmt struct {
-	Name   Token
-	Params []Token
-	Body   []Stmt
+	Name        Token
+	Params      []Token
+	ParamTypes  []*TypeAnnotation // one per parameter, nil when not annotated
+	ReturnType  *TypeAnnotation
+	Body        []Stmt
+	IsGenerator bool // the body yields
 }
 
 func (x *FunctionStmt) Accept(visitor StmtVisitor) error {
@@ -91,9 +123,42 @@ func (x *ReturnStmt) Accept(visitor StmtVisitor) error {
 type ClassStmt struct {
 	Name       Token
 	Superclass *Variable
+	Traits     []*Variable
+	Fields     []*FieldDecl
 	Methods    []*FunctionStmt
 }
 
 func (x *ClassStmt) Accept(visitor StmtVisitor) error {
 	return visitor.VisitClassStmt(x)
 }
+
+type TraitStmt struct {
+	Name    Token
+	Methods []*FunctionStmt
+}
+
+func (x *TraitStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitTraitStmt(x)
+}
+
+type YieldStmt struct {
+	Keyword Token
+	Value   Expr
+}
+
+func (x *YieldStmt) Accept(visitor StmtVisitor) error {
+	return visitor.VisitYieldStmt(x)
+}
+
+// TypeAnnotation is the type a variable, parameter, field or function result
+// is declared with. Annotations are only read by the checker; the interpreter
+// ignores them.
+type TypeAnnotation struct {
+	Name Token
+}
+
+// FieldDecl declares the type of a field in a class body.
+type FieldDecl struct {
+	Name Token
+	Type *TypeAnnotation
+}
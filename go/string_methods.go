@@ -0,0 +1,242 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringMethod returns the method of s called name, bound to s. Lengths and
+// indices count characters, not bytes. The strings methods return are charged
+// to the program's memory.
+func stringMethod(s string, name Token) (any, error) {
+	method := func(arity int, fn func(interpreter *Interpreter, arguments []any) (any, error)) (any, error) {
+		return &nativeFunction{name.Lexeme, arity, func(interpreter *Interpreter, arguments []any) (any, error) {
+			result, err := fn(interpreter, arguments)
+			if err != nil {
+				return nil, err
+			}
+
+			if result, ok := result.(string); ok {
+				if err = interpreter.allocateString(len(result)); err != nil {
+					return nil, err
+				}
+			}
+
+			return result, nil
+		}}, nil
+	}
+
+	switch name.Lexeme {
+	case "len":
+		return method(0, func(_ *Interpreter, _ []any) (any, error) {
+			return float64(utf8.RuneCountInString(s)), nil
+		})
+	case "substr":
+		return method(2, func(_ *Interpreter, arguments []any) (any, error) {
+			start, err := integerArgument("substr", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			end, err := integerArgument("substr", arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			runes := []rune(s)
+			if start < 0 || end > len(runes) || start > end {
+				return nil, nativeErrorf("substring [%d, %d) out of range for length %d", start, end, len(runes))
+			}
+
+			return string(runes[start:end]), nil
+		})
+	case "indexOf":
+		return method(1, func(_ *Interpreter, arguments []any) (any, error) {
+			substring, err := stringArgument("indexOf", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			i := strings.Index(s, substring)
+			if i < 0 {
+				return float64(-1), nil
+			}
+
+			return float64(utf8.RuneCountInString(s[:i])), nil
+		})
+	case "split":
+		return method(1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			separator, err := stringArgument("split", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			parts := strings.Split(s, separator)
+
+			if err = interpreter.allocateList(len(parts)); err != nil {
+				return nil, err
+			}
+
+			if err = interpreter.allocate(len(parts)*stringSize + len(s)); err != nil {
+				return nil, err
+			}
+
+			elements := make([]any, 0, len(parts))
+			for _, part := range parts {
+				elements = append(elements, part)
+			}
+
+			return newList(elements), nil
+		})
+	case "join":
+		return method(1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			list, err := listArgument("join", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			elements := list.snapshot()
+
+			parts := make([]string, 0, len(elements))
+			for _, element := range elements {
+				part, err := interpreter.stringify(element)
+				if err != nil {
+					return nil, err
+				}
+
+				parts = append(parts, part)
+			}
+
+			return strings.Join(parts, s), nil
+		})
+	case "replace":
+		return method(2, func(interpreter *Interpreter, arguments []any) (any, error) {
+			old, err := stringArgument("replace", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			replacement, err := stringArgument("replace", arguments, 1)
+			if err != nil {
+				return nil, err
+			}
+
+			matches := strings.Count(s, old)
+			if !interpreter.fits(len(s) + matches*(len(replacement)-len(old))) {
+				return nil, ErrMemoryLimit
+			}
+
+			return strings.ReplaceAll(s, old, replacement), nil
+		})
+	case "trim":
+		return method(0, func(_ *Interpreter, _ []any) (any, error) {
+			return strings.TrimSpace(s), nil
+		})
+	case "upper":
+		return method(0, func(_ *Interpreter, _ []any) (any, error) {
+			return strings.ToUpper(s), nil
+		})
+	case "lower":
+		return method(0, func(_ *Interpreter, _ []any) (any, error) {
+			return strings.ToLower(s), nil
+		})
+	case "startsWith":
+		return method(1, func(_ *Interpreter, arguments []any) (any, error) {
+			prefix, err := stringArgument("startsWith", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			return strings.HasPrefix(s, prefix), nil
+		})
+	case "repeat":
+		return method(1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			count, err := integerArgument("repeat", arguments, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			if count < 0 {
+				return nil, nativeErrorf("repeat count must not be negative")
+			}
+
+			if !interpreter.fits(len(s) * count) {
+				return nil, ErrMemoryLimit
+			}
+
+			return strings.Repeat(s, count), nil
+		})
+	case "format":
+		return method(-1, func(interpreter *Interpreter, arguments []any) (any, error) {
+			return formatString(interpreter, s, arguments)
+		})
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+// formatString replaces each {} in template with the next argument and each
+// {N} with argument N, counting from 0. {{ and }} stand for literal braces.
+func formatString(interpreter *Interpreter, template string, arguments []any) (string, error) {
+	var builder strings.Builder
+
+	next := 0
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+
+		if c == '}' {
+			if i+1 < len(template) && template[i+1] == '}' {
+				i++
+			}
+
+			builder.WriteByte('}')
+			continue
+		}
+
+		if c != '{' {
+			builder.WriteByte(c)
+			continue
+		}
+
+		if i+1 < len(template) && template[i+1] == '{' {
+			builder.WriteByte('{')
+			i++
+			continue
+		}
+
+		end := strings.IndexByte(template[i:], '}')
+		if end < 0 {
+			return "", nativeErrorf("unclosed '{' in format string")
+		}
+
+		placeholder := template[i+1 : i+end]
+		i += end
+
+		argument := next
+		if placeholder == "" {
+			next++
+		} else {
+			n, err := strconv.Atoi(placeholder)
+			if err != nil || n < 0 {
+				return "", nativeErrorf("invalid format placeholder '{%s}'", placeholder)
+			}
+
+			argument = n
+		}
+
+		if argument >= len(arguments) {
+			return "", nativeErrorf("format placeholder %d has no argument", argument)
+		}
+
+		s, err := interpreter.stringify(arguments[argument])
+		if err != nil {
+			return "", err
+		}
+
+		builder.WriteString(s)
+	}
+
+	return builder.String(), nil
+}
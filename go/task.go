@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// errProgramFinished stops the tasks still running when the Interpret call
+// that spawned them returns.
+var errProgramFinished = errors.New("program finished")
+
+// errDeadlock stops a program whose main program and tasks all wait on
+// channels or tasks, since none of them can ever wake the others.
+var errDeadlock = nativeErrorf("deadlock: all tasks are blocked")
+
+// TaskImpl is what spawn returns: a call running on a goroutine of its own,
+// on an interpreter forked from the one that spawned it.
+type TaskImpl struct {
+	value any
+	err   error
+
+	// Guarded by the budget's blockMutex.
+	finished bool
+	waiters  []*waiter
+}
+
+// console serializes the use of standard input and output by the tasks of a
+// program, so lines they print don't interleave.
+type console struct {
+	input  sync.Mutex
+	output sync.Mutex
+}
+
+// spawn calls fn on a new task. A task that fails stops the whole program
+// with its error.
+func (x *Interpreter) spawn(fn Callable, arguments []any, token Token) *TaskImpl {
+	task := &TaskImpl{}
+	fork := x.fork()
+
+	x.budget.tasks.Add(1)
+	x.budget.started()
+
+	go func() {
+		defer x.budget.tasks.Done()
+		defer x.budget.finished(task)
+
+		task.value, task.err = fork.call(fn, arguments, token)
+
+		if task.err != nil && !errors.Is(task.err, errProgramFinished) {
+			x.budget.stop(task.err)
+		}
+	}()
+
+	return task
+}
+
+// finishTasks stops the tasks still running when the program ends and waits
+// for them to unwind, then closes the generators left suspended unless the
+// next program may resume them.
+func (x *Interpreter) finishTasks() {
+	x.budget.stop(errProgramFinished)
+	x.budget.tasks.Wait()
+
+	if !x.Interactive {
+		x.budget.closeGenerators()
+	}
+}
+
+// waiter is a task blocked on channels or on another task, until one of them
+// completes it. Its fields are guarded by the budget's blockMutex.
+type waiter struct {
+	wake    chan struct{} // closed once done is set
+	done    bool
+	channel *ChannelImpl // the channel that completed it
+	value   any          // the value sent, or received
+	ok      bool         // whether value was passed on, rather than the channel closed
+}
+
+func newWaiter() *waiter {
+	return &waiter{wake: make(chan struct{})}
+}
+
+// start counts the main program as the only task running, none blocked.
+func (x *budget) start() {
+	x.blockMutex.Lock()
+	defer x.blockMutex.Unlock()
+
+	x.running, x.blocked = 1, 0
+}
+
+// started counts a newly spawned task.
+func (x *budget) started() {
+	x.blockMutex.Lock()
+	defer x.blockMutex.Unlock()
+
+	x.running++
+}
+
+// finished completes the waiters of task, which has finished, and counts it
+// out, which may leave only blocked ones.
+func (x *budget) finished(task *TaskImpl) {
+	x.blockMutex.Lock()
+	defer x.blockMutex.Unlock()
+
+	task.finished = true
+
+	for _, waiter := range task.waiters {
+		x.complete(waiter)
+	}
+
+	task.waiters = nil
+
+	x.running--
+	x.checkDeadlock()
+}
+
+// park counts a task that starts waiting as blocked, and stops the program
+// with errDeadlock if that leaves none running, since none could ever wake
+// it. It must be called with blockMutex held.
+func (x *budget) park() {
+	x.blocked++
+	x.checkDeadlock()
+}
+
+// complete wakes waiter, unless it's already done. It must be called with
+// blockMutex held.
+func (x *budget) complete(waiter *waiter) {
+	if waiter.done {
+		return
+	}
+
+	waiter.done = true
+	x.blocked--
+	close(waiter.wake)
+}
+
+// wait waits until waiter is completed, or gives it up if the program is
+// interrupted first.
+func (x *budget) wait(waiter *waiter) error {
+	select {
+	case <-waiter.wake:
+		return nil
+	case <-x.interrupted:
+	}
+
+	x.blockMutex.Lock()
+	defer x.blockMutex.Unlock()
+
+	if waiter.done {
+		return nil
+	}
+
+	// The channels and tasks it waits on skip it from now on.
+	waiter.done = true
+	x.blocked--
+
+	return x.interruption()
+}
+
+// checkDeadlock stops the program with errDeadlock if every task is blocked.
+// It must be called with blockMutex held.
+func (x *budget) checkDeadlock() {
+	if x.running > 0 && x.blocked == x.running {
+		x.stop(errDeadlock)
+	}
+}
+
+// Get returns the method of x called name, bound to x.
+func (x *TaskImpl) Get(name Token) (any, error) {
+	switch name.Lexeme {
+	case "wait":
+		return &nativeFunction{"wait", 0, func(interpreter *Interpreter, _ []any) (any, error) {
+			budget := interpreter.budget
+
+			budget.blockMutex.Lock()
+
+			if x.finished {
+				budget.blockMutex.Unlock()
+			} else {
+				waiter := newWaiter()
+				x.waiters = append(x.waiters, waiter)
+				budget.park()
+				budget.blockMutex.Unlock()
+
+				if err := budget.wait(waiter); err != nil {
+					return nil, err
+				}
+			}
+
+			// A task stopped along with the program, by a deadlock for
+			// one, fails with the program's error rather than its own.
+			if err := budget.interruption(); err != nil {
+				return nil, err
+			}
+
+			if errors.Is(x.err, errProgramFinished) {
+				return nil, nativeErrorf("task was stopped before it finished")
+			}
+
+			return x.value, x.err
+		}}, nil
+	}
+
+	return nil, RuntimeError{"undefined property '" + name.Lexeme + "'", name}
+}
+
+func (x *TaskImpl) String() string {
+	return "<task>"
+}
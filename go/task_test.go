@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestTasksShareHeap has tasks update the same variables, list, map and
+// instance at once; run it with -race to check that sharing them is safe.
+func TestTasksShareHeap(t *testing.T) {
+	const program = `
+class Counter {}
+
+var counter = Counter();
+counter.count = 0;
+var total = 0;
+var items = list();
+var names = map();
+var done = channel();
+
+fun work(id) {
+  for (var i = 0; i < 50; i = i + 1) {
+    counter.count = i;
+    total = i;
+    items.push(i);
+    names.set("abcdefgh".substr(id, id + 1), i);
+    names.get("a");
+    print items.len();
+  }
+  done.send(id);
+}
+
+for (var id = 0; id < 8; id = id + 1) spawn work(id);
+for (var id = 0; id < 8; id = id + 1) done.receive();
+
+print items.len();
+print names.len();
+`
+
+	statements, err := parse(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+
+	interpreter := (&Interpreter{Stdout: &stdout}).Init()
+	if err = interpreter.Resolve(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = interpreter.Interpret(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(stdout.Bytes()), []byte("\n"))
+	if len(lines) != 8*50+2 {
+		t.Fatalf("expected %d lines, got %d", 8*50+2, len(lines))
+	}
+
+	if got := string(lines[len(lines)-2]); got != "400" {
+		t.Errorf("expected 400 items, got %s", got)
+	}
+
+	if got := string(lines[len(lines)-1]); got != "8" {
+		t.Errorf("expected 8 names, got %s", got)
+	}
+}
+
+// TestTasksStopWithProgram checks Interpret doesn't leave tasks blocked on a
+// channel behind.
+func TestTasksStopWithProgram(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	err := interpretWithLimits(context.Background(), Limits{}, `
+fun forever(c) { c.receive(); }
+for (var i = 0; i < 10; i = i + 1) spawn forever(channel());
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines left running, started with %d", after, before)
+	}
+}
+
+// TestTimeoutStopsTasks checks a timeout interrupts tasks and the program
+// waiting on them.
+func TestTimeoutStopsTasks(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{Timeout: 50 * time.Millisecond}, `
+fun spin() { while (true) {} }
+var c = channel();
+spawn spin();
+c.receive();
+`)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %v, got %v", ErrTimeout, err)
+	}
+}
+
+// TestTaskErrorStopsProgram checks a failing task ends the program even when
+// no one waits for it.
+func TestTaskErrorStopsProgram(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{}, `
+fun fail() { nil(); }
+spawn fail();
+while (true) {}
+`)
+
+	var runtimeErr RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Token.Line != 2 {
+		t.Errorf("expected the task's runtime error, got %v", err)
+	}
+}
+
+// TestDeadlockStopsProgram checks a program whose tasks all wait on each
+// other fails instead of hanging.
+func TestDeadlockStopsProgram(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{Timeout: 5 * time.Second}, `
+fun relay(from, to) { to.send(from.receive()); }
+var a = channel();
+var b = channel();
+spawn relay(a, b);
+var task = spawn relay(b, a);
+task.wait();
+`)
+
+	var runtimeErr RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "deadlock: all tasks are blocked" || runtimeErr.Token.Line != 7 {
+		t.Errorf("expected a deadlock at the wait, got %v", err)
+	}
+}
+
+// TestBusyChannelsAreNotDeadlocked checks tasks that keep waiting on each
+// other while they hand values along aren't taken for a deadlock.
+func TestBusyChannelsAreNotDeadlocked(t *testing.T) {
+	err := interpretWithLimits(context.Background(), Limits{}, `
+fun echo(source, out) {
+  var value;
+  while ((value = source.receive()) != nil) out.send(value);
+  out.close();
+}
+
+var first = channel();
+var last = first;
+for (var i = 0; i < 8; i = i + 1) {
+  var next = channel();
+  spawn echo(last, next);
+  last = next;
+}
+
+for (var i = 1; i <= 2000; i = i + 1) {
+  first.send(i);
+  last.receive();
+}
+first.close();
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "time"
+
+// seconds converts t to seconds since the Unix epoch, keeping the fraction.
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+func newTimeModule() *moduleImpl {
+	module := &moduleImpl{name: "time", members: map[string]any{}}
+
+	// Durations measured against start use the monotonic clock, so they're
+	// immune to changes of the wall clock.
+	start := time.Now()
+
+	module.define("now", 0, func(_ *Interpreter, _ []any) (any, error) {
+		return seconds(time.Now()), nil
+	})
+	module.define("monotonic", 0, func(_ *Interpreter, _ []any) (any, error) {
+		return time.Since(start).Seconds(), nil
+	})
+	module.define("sleep", 1, func(interpreter *Interpreter, arguments []any) (any, error) {
+		duration, err := numberArgument("time.sleep", arguments, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if duration <= 0 {
+			return nil, nil
+		}
+
+		timer := time.NewTimer(time.Duration(duration * float64(time.Second)))
+		defer timer.Stop()
+
+		// Sleeping doesn't keep a program from being stopped.
+		select {
+		case <-timer.C:
+			return nil, nil
+		case <-interpreter.budget.interrupted:
+			return nil, interpreter.budget.interruption()
+		}
+	})
+
+	return module
+}
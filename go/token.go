@@ -7,6 +7,7 @@ type Token struct {
 	Lexeme  string
 	Literal any
 	Line    int
+	Column  int
 }
 
 func (x *Token) String() string {
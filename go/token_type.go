@@ -4,27 +4,43 @@ type TokenType string
 
 const (
 	// Single-character tokens
-	LeftParen  TokenType = "LEFT_PAREN"
-	RightParen TokenType = "RIGHT_PAREN"
-	LeftBrace  TokenType = "LEFT_BRACE"
-	RightBrace TokenType = "RIGHT_BRACE"
-	Comma      TokenType = "COMMA"
-	Dot        TokenType = "DOT"
-	Minus      TokenType = "MINUS"
-	Plus       TokenType = "PLUS"
-	Semicolon  TokenType = "SEMICOLON"
-	Slash      TokenType = "SLASH"
-	Star       TokenType = "STAR"
+	LeftParen    TokenType = "LEFT_PAREN"
+	RightParen   TokenType = "RIGHT_PAREN"
+	LeftBrace    TokenType = "LEFT_BRACE"
+	RightBrace   TokenType = "RIGHT_BRACE"
+	LeftBracket  TokenType = "LEFT_BRACKET"
+	RightBracket TokenType = "RIGHT_BRACKET"
+	Colon        TokenType = "COLON"
+	Comma        TokenType = "COMMA"
+	Dot          TokenType = "DOT"
+	Minus        TokenType = "MINUS"
+	Percent      TokenType = "PERCENT"
+	Pipe         TokenType = "PIPE"
+	Plus         TokenType = "PLUS"
+	Question     TokenType = "QUESTION"
+	Semicolon    TokenType = "SEMICOLON"
+	Slash        TokenType = "SLASH"
+	Star         TokenType = "STAR"
 
 	// One or two character tokens
-	Bang         TokenType = "BANG"
-	BangEqual    TokenType = "BANG_EQUAL"
-	Equal        TokenType = "EQUAL"
-	EqualEqual   TokenType = "EQUAL_EQUAL"
-	Greater      TokenType = "GREATER"
-	GreaterEqual TokenType = "GREATER_EQUAL"
-	Less         TokenType = "LESS"
-	LessEqual    TokenType = "LESS_EQUAL"
+	Bang             TokenType = "BANG"
+	BangEqual        TokenType = "BANG_EQUAL"
+	Equal            TokenType = "EQUAL"
+	EqualEqual       TokenType = "EQUAL_EQUAL"
+	FatArrow         TokenType = "FAT_ARROW"
+	Greater          TokenType = "GREATER"
+	GreaterEqual     TokenType = "GREATER_EQUAL"
+	Less             TokenType = "LESS"
+	LessEqual        TokenType = "LESS_EQUAL"
+	MinusEqual       TokenType = "MINUS_EQUAL"
+	MinusMinus       TokenType = "MINUS_MINUS"
+	PercentEqual     TokenType = "PERCENT_EQUAL"
+	PlusEqual        TokenType = "PLUS_EQUAL"
+	PlusPlus         TokenType = "PLUS_PLUS"
+	QuestionDot      TokenType = "QUESTION_DOT"
+	QuestionQuestion TokenType = "QUESTION_QUESTION"
+	SlashEqual       TokenType = "SLASH_EQUAL"
+	StarEqual        TokenType = "STAR_EQUAL"
 
 	// Literals
 	Identifier TokenType = "IDENTIFIER"
@@ -33,21 +49,33 @@ const (
 
 	// Keywords
 	And    TokenType = "AND"
+	Case   TokenType = "CASE"
 	Class  TokenType = "CLASS"
+	Const  TokenType = "CONST"
 	Else   TokenType = "ELSE"
 	False  TokenType = "FALSE"
 	Fun    TokenType = "FUN"
 	For    TokenType = "FOR"
 	If     TokenType = "IF"
+	In     TokenType = "IN"
+	Is     TokenType = "IS"
+	Match  TokenType = "MATCH"
 	Nil    TokenType = "NIL"
 	Or     TokenType = "OR"
 	Print  TokenType = "PRINT"
 	Return TokenType = "RETURN"
+	Spawn  TokenType = "SPAWN"
 	Super  TokenType = "SUPER"
 	This   TokenType = "THIS"
+	Trait  TokenType = "TRAIT"
 	True   TokenType = "TRUE"
 	Var    TokenType = "VAR"
 	While  TokenType = "WHILE"
+	With   TokenType = "WITH"
+	Yield  TokenType = "YIELD"
+
+	// Trivia, only emitted by scanners created with NewTriviaScanner
+	Comment TokenType = "COMMENT"
 
 	EOF TokenType = "EOF"
 )
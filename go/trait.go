@@ -0,0 +1,109 @@
+package main
+
+import "fmt"
+
+// TraitImpl is a named set of methods that classes mix in with 'with'. Its
+// methods are closed over an environment holding a nil 'super', which
+// mixing replaces with the superclass of the class they are copied into.
+type TraitImpl struct {
+	name    string
+	methods map[string]*FunctionImpl
+}
+
+func (x *TraitImpl) String() string {
+	return x.name
+}
+
+func (x *Interpreter) VisitTraitStmt(stmt *TraitStmt) error {
+	if err := x.checkRedeclaration(stmt.Name); err != nil {
+		return err
+	}
+
+	environment := &Environment{
+		values:    map[string]any{"super": nil},
+		enclosing: x.environment,
+	}
+
+	methods := make(map[string]*FunctionImpl)
+	for _, method := range stmt.Methods {
+		methods[method.Name.Lexeme] = &FunctionImpl{
+			declaration:   method,
+			closure:       environment,
+			isInitializer: method.Name.Lexeme == "init",
+		}
+	}
+
+	x.environment.Define(stmt.Name.Lexeme, &TraitImpl{name: stmt.Name.Lexeme, methods: methods})
+
+	return nil
+}
+
+// mixTraits evaluates the traits of a class and adds their methods to
+// methods, which holds the class's own methods and wins over them. Two
+// traits may not provide the same method unless the class overrides it.
+func (x *Interpreter) mixTraits(stmt *ClassStmt, methods map[string]*FunctionImpl, superclass *ClassImpl) ([]*TraitImpl, error) {
+	own := make(map[string]bool, len(methods))
+	for name := range methods {
+		own[name] = true
+	}
+
+	// An untyped nil, so that 'super' in a class without one fails cleanly.
+	var super any
+	if superclass != nil {
+		super = superclass
+	}
+
+	providers := make(map[string]*TraitImpl)
+
+	var traits []*TraitImpl
+
+	for _, variable := range stmt.Traits {
+		value, err := x.evaluate(variable)
+		if err != nil {
+			return nil, err
+		}
+
+		trait, ok := value.(*TraitImpl)
+		if !ok {
+			return nil, RuntimeError{"can only mix in traits", variable.Name}
+		}
+
+		traits = append(traits, trait)
+
+		for name, method := range trait.methods {
+			if own[name] {
+				continue
+			}
+
+			if provider, ok := providers[name]; ok {
+				return nil, RuntimeError{fmt.Sprintf("method '%s' is defined by both traits '%s' and '%s'", name, provider.name, trait.name), stmt.Name}
+			}
+
+			providers[name] = trait
+
+			methods[name] = &FunctionImpl{
+				declaration: method.declaration,
+				closure: &Environment{
+					values:    map[string]any{"super": super},
+					enclosing: method.closure.enclosing,
+				},
+				isInitializer: method.isInitializer,
+			}
+		}
+	}
+
+	return traits, nil
+}
+
+// hasTrait reports whether c or one of its superclasses mixes in trait.
+func (c *ClassImpl) hasTrait(trait *TraitImpl) bool {
+	for ; c != nil; c = c.superclass {
+		for _, t := range c.traits {
+			if t == trait {
+				return true
+			}
+		}
+	}
+
+	return false
+}